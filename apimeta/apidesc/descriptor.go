@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/vizee/gapi-plus/apimeta"
 	"github.com/vizee/gapi-plus/apimeta/internal/helpers"
 	"github.com/vizee/gapi-plus/apimeta/internal/slices"
 	"github.com/vizee/gapi-plus/proto/descriptor"
@@ -14,7 +15,38 @@ import (
 )
 
 type ResolvingCache struct {
-	msgs map[string]*helpers.Message
+	// TimeoutPolicy 不为 nil 时用于校验方法的超时时间
+	TimeoutPolicy *apimeta.TimeoutPolicy
+	// StrictFields 为 true 时，引用了被丢弃字段的方法会被视为错误
+	StrictFields bool
+
+	msgs        map[string]*helpers.Message
+	diags       []apimeta.Diagnostic
+	dropped     []apimeta.DroppedField
+	droppedMsgs map[*jsonpb.Message]bool
+}
+
+// Diagnostics 返回解析路由过程中产生的诊断信息
+func (rc *ResolvingCache) Diagnostics() []apimeta.Diagnostic {
+	return rc.diags
+}
+
+// DroppedFields 返回所有已解析消息中因为类型不支持而被丢弃的字段
+func (rc *ResolvingCache) DroppedFields() []apimeta.DroppedField {
+	return rc.dropped
+}
+
+func (rc *ResolvingCache) dropField(msg *helpers.Message, fd *descriptor.FieldDesc, reason string) {
+	rc.dropped = append(rc.dropped, apimeta.DroppedField{
+		Message: msg.Name,
+		Field:   fd.Name,
+		Type:    fd.Type,
+		Reason:  reason,
+	})
+	if rc.droppedMsgs == nil {
+		rc.droppedMsgs = make(map[*jsonpb.Message]bool)
+	}
+	rc.droppedMsgs[msg.Message] = true
 }
 
 func (rc *ResolvingCache) resolveMessage(md *descriptor.MessageDesc) *helpers.Message {
@@ -36,9 +68,11 @@ func (rc *ResolvingCache) resolveMessage(md *descriptor.MessageDesc) *helpers.Me
 	// 防止递归
 	rc.msgs[msg.Name] = msg
 
-	for _, fd := range md.Fields {
+	for i := range md.Fields {
+		fd := &md.Fields[i]
 		kind, ok := helpers.GetTypeKind(fd.Type)
 		if !ok {
+			rc.dropField(msg, fd, "unsupported field type")
 			continue
 		}
 		name := fd.Name
@@ -46,16 +80,17 @@ func (rc *ResolvingCache) resolveMessage(md *descriptor.MessageDesc) *helpers.Me
 			name = fd.Alias
 		}
 
-		if fd.Bind == annotation.FIELD_BIND_FROM_DEFAULT {
-			repeated := fd.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
-			var msgRef *jsonpb.Message
-			if kind == jsonpb.MessageKind {
-				msgRef = rc.resolveMessage(fd.Ref).Message
-				if fd.Ref.MapEntry {
-					kind = jsonpb.MapKind
-					repeated = false
-				}
+		repeated := fd.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		var msgRef *jsonpb.Message
+		if kind == jsonpb.MessageKind {
+			msgRef = rc.resolveMessage(fd.Ref).Message
+			if fd.Ref.MapEntry {
+				kind = jsonpb.MapKind
+				repeated = false
 			}
+		}
+
+		if fd.Bind == annotation.FIELD_BIND_FROM_DEFAULT {
 			omit := jsonpb.OmitProtoEmpty
 			if fd.OmitEmpty {
 				omit = jsonpb.OmitEmpty
@@ -80,11 +115,15 @@ func (rc *ResolvingCache) resolveMessage(md *descriptor.MessageDesc) *helpers.Me
 			case annotation.FIELD_BIND_FROM_CONTEXT:
 				bind = metadata.BindContext
 			}
-			msg.Bindings = append(msg.Bindings, metadata.FieldBinding{
-				Name: name,
-				Kind: kind,
-				Tag:  uint32(fd.Tag),
-				Bind: bind,
+			msg.Bindings = append(msg.Bindings, helpers.Binding{
+				FieldBinding: metadata.FieldBinding{
+					Name: name,
+					Kind: kind,
+					Tag:  uint32(fd.Tag),
+					Bind: bind,
+				},
+				Repeated: repeated,
+				Ref:      msgRef,
 			})
 		}
 	}
@@ -98,7 +137,22 @@ func (rc *ResolvingCache) resolveMessage(md *descriptor.MessageDesc) *helpers.Me
 	return msg
 }
 
+// ResolveRoutes 解析服务中的路由。注释中注解的错误不会丢弃路由，只作为警告记录在 Diagnostics 中
 func ResolveRoutes(rc *ResolvingCache, sds []*descriptor.ServiceDesc, ignoreError bool) ([]*metadata.Route, error) {
+	routes, err := resolveRoutes(rc, sds, ignoreError, true)
+	if err != nil {
+		return nil, err
+	}
+	return apimeta.MetadataRoutes(routes), nil
+}
+
+// ResolveAPIRoutes 与 ResolveRoutes 相同，但是返回的路由附带了注释中的文档信息和源码位置，注解的错误与其他错误一样处理
+func ResolveAPIRoutes(rc *ResolvingCache, sds []*descriptor.ServiceDesc, ignoreError bool) ([]*apimeta.Route, error) {
+	return resolveRoutes(rc, sds, ignoreError, false)
+}
+
+// resolveRoutes 解析服务中的路由，legacy 为 true 时注解的错误只记录为警告
+func resolveRoutes(rc *ResolvingCache, sds []*descriptor.ServiceDesc, ignoreError bool, legacy bool) ([]*apimeta.Route, error) {
 	routesNum := 0
 	for _, sd := range sds {
 		if sd.Opts.Server == "" {
@@ -111,7 +165,7 @@ func ResolveRoutes(rc *ResolvingCache, sds []*descriptor.ServiceDesc, ignoreErro
 			routesNum++
 		}
 	}
-	routes := make([]*metadata.Route, 0, routesNum)
+	routes := make([]*apimeta.Route, 0, routesNum)
 walksd:
 	for _, sd := range sds {
 		server := sd.Opts.Server
@@ -122,18 +176,45 @@ walksd:
 			return nil, errors.New("invalid service '" + sd.Name + "'")
 		}
 		for _, use := range sd.Opts.Use {
-			if !helpers.CheckMiddlewareName(use) {
+			if !apimeta.CheckMiddlewareName(use) {
 				if ignoreError {
 					continue walksd
 				}
 				return nil, errors.New("invalid middleware name '" + use + "'")
 			}
 		}
+		serviceAns := helpers.ExtractAnnotations(sd.Source.Comments)
+		var serviceAttrs apimeta.Attributes
+		serviceHosts, err := helpers.ParseHosts(serviceAns)
+		if err == nil {
+			serviceAttrs, err = helpers.ParseAttributes(serviceAns)
+		}
+		if err != nil {
+			switch {
+			case legacy:
+				rc.diags = append(rc.diags, *helpers.AnnotationDiagnostic(sd.FullName, apimeta.SourceLocation{File: sd.Source.File, Line: sd.Source.Line, Column: sd.Source.Column}, err))
+			case ignoreError:
+				continue
+			default:
+				return nil, err
+			}
+		}
+		service := &apimeta.Service{
+			Name:           sd.FullName,
+			File:           sd.Source.File,
+			Server:         server,
+			PathPrefix:     sd.Opts.PathPrefix,
+			DefaultHandler: sd.Opts.DefaultHandler,
+			DefaultTimeout: time.Duration(sd.Opts.DefaultTimeout) * time.Millisecond,
+			Use:            sd.Opts.Use,
+			Hosts:          serviceHosts,
+			Attributes:     serviceAttrs,
+		}
 
 	walkmd:
 		for _, md := range sd.Methods {
 			for _, use := range md.Opts.Use {
-				if !helpers.CheckMiddlewareName(use) {
+				if !apimeta.CheckMiddlewareName(use) {
 					if ignoreError {
 						continue walkmd
 					}
@@ -145,7 +226,38 @@ walksd:
 			if handler == "" {
 				handler = sd.Opts.DefaultHandler
 			}
-			if handler == "" || md.Streaming || md.Opts.Method == "" || md.Opts.Path == "" || md.In == nil || md.In.Incomplete || md.Out == nil || md.Out.Incomplete {
+			if md.Streaming || md.Opts.Method == "" || md.Opts.Path == "" || md.In == nil || md.In.Incomplete || md.Out == nil || md.Out.Incomplete {
+				if ignoreError {
+					continue
+				}
+				return nil, errors.New("invalid method '" + md.Name + "'")
+			}
+
+			fullMethod := helpers.ConcatFullMethodName(sd.FullName, md.Name)
+			route := &apimeta.Route{
+				Source: apimeta.SourceLocation{
+					File:   md.Source.File,
+					Line:   md.Source.Line,
+					Column: md.Source.Column,
+				},
+				Service: service,
+			}
+			err := helpers.FillRouteAnnotations(route, serviceAns, helpers.ExtractAnnotations(md.Source.Comments))
+			if err != nil {
+				switch {
+				case legacy:
+					rc.diags = append(rc.diags, *helpers.AnnotationDiagnostic(fullMethod, route.Source, err))
+				case ignoreError:
+					continue
+				default:
+					return nil, err
+				}
+			}
+			// 没有指定 handler 时使用第一个按媒体类型协商的 handler
+			if handler == "" && len(route.Handlers) > 0 {
+				handler = route.Handlers[0].Handler
+			}
+			if handler == "" {
 				if ignoreError {
 					continue
 				}
@@ -156,22 +268,68 @@ walksd:
 			if timeout == 0 {
 				timeout = sd.Opts.DefaultTimeout
 			}
+			timeoutDuration, diag := helpers.ResolveTimeout(rc.TimeoutPolicy, server, fullMethod, route.Source, timeout)
+			if diag != nil {
+				rc.diags = append(rc.diags, *diag)
+				if diag.Severity == apimeta.SeverityError {
+					if ignoreError {
+						continue
+					}
+					return nil, diag
+				}
+			}
 
 			inMsg := rc.resolveMessage(md.In)
-			routes = append(routes, &metadata.Route{
+			outMsg := rc.resolveMessage(md.Out)
+			if rc.StrictFields && (helpers.HasDroppedField(inMsg.Message, rc.droppedMsgs) || helpers.HasDroppedField(outMsg.Message, rc.droppedMsgs)) {
+				diag := &apimeta.Diagnostic{
+					Severity: apimeta.SeverityError,
+					Method:   fullMethod,
+					Source:   route.Source,
+					Message:  "method '" + md.Name + "' references messages with dropped fields",
+				}
+				rc.diags = append(rc.diags, *diag)
+				if ignoreError {
+					continue
+				}
+				return nil, diag
+			}
+
+			bindings, callBindings, nested, err := helpers.ResolveRequestBindings(inMsg.Bindings)
+			if err != nil {
+				if ignoreError {
+					continue
+				}
+				return nil, errors.New("invalid method '" + md.Name + "': " + err.Error())
+			}
+			route.Bindings = bindings
+			if diag := helpers.NestedBindingDiagnostic(fullMethod, route.Source, nested); diag != nil {
+				rc.diags = append(rc.diags, *diag)
+			}
+			respBindings, err := helpers.ResolveResponseBindings(outMsg.Bindings)
+			if err != nil {
+				if ignoreError {
+					continue
+				}
+				return nil, errors.New("invalid method '" + md.Name + "': " + err.Error())
+			}
+			route.ResponseBindings = respBindings
+
+			route.Route = &metadata.Route{
 				Method: md.Opts.Method,
 				Path:   sd.Opts.PathPrefix + md.Opts.Path,
 				Use:    slices.Merge(sd.Opts.Use, md.Opts.Use),
 				Call: &metadata.Call{
 					Server:   server,
 					Handler:  handler,
-					Method:   helpers.ConcatFullMethodName(sd.FullName, md.Name),
+					Method:   fullMethod,
 					In:       inMsg.Message,
-					Out:      rc.resolveMessage(md.Out).Message,
-					Bindings: inMsg.Bindings,
-					Timeout:  time.Duration(timeout) * time.Millisecond,
+					Out:      outMsg.Message,
+					Bindings: callBindings,
+					Timeout:  timeoutDuration,
 				},
-			})
+			}
+			routes = append(routes, route)
 		}
 	}
 
@@ -3,9 +3,12 @@ package apidesc
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/vizee/gapi-plus/apimeta"
 	"github.com/vizee/gapi-plus/proto/descriptor"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -38,3 +41,150 @@ func TestResolveRoutes(t *testing.T) {
 	}
 	t.Log(string(j))
 }
+
+func TestResolveAPIRoutes(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/pdtest/pdtest.pd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := descriptor.NewParser()
+	for _, fd := range fds.File {
+		err := p.AddFile(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rc := &ResolvingCache{}
+	routes, err := ResolveAPIRoutes(rc, p.Services(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range routes {
+		if r.Source.File != "pdtest.proto" {
+			t.Fatalf("route %s source = %s", r.Path, r.Source)
+		}
+		switch r.Path {
+		case "/path/prefix/add":
+			if r.Summary != "Add" || r.Description != "加法" || !r.Deprecated || len(r.ResponseBindings) != 2 || r.ResponseBindings[1].Target != apimeta.ResponseStatus || len(r.Handlers) != 2 || r.Handlers[1].Handler != "pbapi" || r.Retry == nil || r.Retry.MaxAttempts != 3 || !r.MatchHost("a.partner.example.com") {
+				t.Fatalf("route %s: %+v", r.Path, r)
+			}
+		case "/path/prefix/say":
+			if r.Summary != "Say" || r.Deprecated || r.FieldMask != "fields" || r.Cache == nil || r.Cache.CacheControl() != "private, max-age=30" || r.Retry != nil ||
+				len(r.Attributes) != 3 || r.Attributes["body.max-bytes"] != "4096" || r.Attributes["cors.origins"] != "*" || r.Attributes["feature.beta"] != "true" || len(r.Hosts) != 1 || r.MatchHost("api.example.com") {
+				t.Fatalf("route %s: %+v", r.Path, r)
+			}
+			if len(r.Bindings) != 4 || !r.Bindings[1].Repeated || r.Bindings[3].Name != "page.offset" || len(r.Bindings[3].Path) != 2 || r.Bindings[3].Path[1] != 2 || len(r.Call.Bindings) != 2 || r.Call.Bindings[1].Name != "tag" {
+				t.Fatalf("route %s bindings: %+v", r.Path, r.Bindings)
+			}
+		}
+	}
+	if diags := rc.Diagnostics(); len(diags) != 1 || diags[0].Severity != apimeta.SeverityWarning || !strings.Contains(diags[0].Message, "'page'") {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+	j, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(j))
+}
+
+func TestResolveRoutesDroppedFields(t *testing.T) {
+	sopts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(sopts, annotation.E_Server, "test-server")
+	proto.SetExtension(sopts, annotation.E_DefaultHandler, "jsonapi")
+	mopts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(mopts, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/legacy"}})
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("legacy.proto"),
+		Package: proto.String("legacy"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("id"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+					{Name: proto.String("result"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum(), TypeName: proto.String(".legacy.Request.Result")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Result")}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name:    proto.String("LegacyService"),
+				Options: sopts,
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: proto.String("Call"), InputType: proto.String(".legacy.Request"), OutputType: proto.String(".legacy.Request"), Options: mopts},
+				},
+			},
+		},
+	}
+	p := descriptor.NewParser()
+	err := p.AddFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc := &ResolvingCache{}
+	routes, err := ResolveRoutes(rc, p.Services(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := rc.DroppedFields()
+	if len(routes) != 1 || len(dropped) != 1 || dropped[0].Message != "legacy.Request" || dropped[0].Field != "result" {
+		t.Fatal("unexpected dropped fields", dropped)
+	}
+
+	rc = &ResolvingCache{StrictFields: true}
+	routes, err = ResolveRoutes(rc, p.Services(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 0 || len(rc.Diagnostics()) != 1 {
+		t.Fatal("expect method to be rejected in strict mode", routes, rc.Diagnostics())
+	}
+}
+
+func TestResolveRoutesAnnotationErrors(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/pdtest/pdtest.pd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := descriptor.NewParser()
+	for _, fd := range fds.File {
+		err := p.AddFile(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sd := range p.Services() {
+		for _, md := range sd.Methods {
+			md.Source.Comments += " @sunset someday\n"
+		}
+	}
+
+	// 只返回 metadata.Route 时注解错误是警告
+	rc := &ResolvingCache{}
+	routes, err := ResolveRoutes(rc, p.Services(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || len(rc.Diagnostics()) != 3 || rc.Diagnostics()[0].Severity != apimeta.SeverityWarning || rc.Diagnostics()[0].Message != "invalid sunset 'someday'" {
+		t.Fatalf("routes = %v, diagnostics = %v", routes, rc.Diagnostics())
+	}
+
+	_, err = ResolveAPIRoutes(&ResolvingCache{}, p.Services(), false)
+	if err == nil || !strings.Contains(err.Error(), "invalid sunset 'someday'") {
+		t.Fatalf("ResolveAPIRoutes() error = %v", err)
+	}
+}
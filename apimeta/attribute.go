@@ -0,0 +1,98 @@
+package apimeta
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attributes 是服务和路由上的自定义属性，通过注释中的 `@attr key=value` 声明，省略 `=value` 时值为 "true"。
+// 路由的属性由服务属性和方法属性合并而成，方法的同名属性覆盖服务的属性。
+// 属性值保存原始文本，由使用属性的中间件按需要的类型读取，建议用 `cors.origins` 这样带前缀的 key 区分不同的功能。
+type Attributes map[string]string
+
+func (a Attributes) Has(key string) bool {
+	_, ok := a[key]
+	return ok
+}
+
+func (a Attributes) String(key string, def string) string {
+	v, ok := a[key]
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Strings 按 ',' 切分属性值并去掉空白项
+func (a Attributes) Strings(key string) []string {
+	var items []string
+	for _, item := range strings.Split(a[key], ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (a Attributes) Bool(key string, def bool) (bool, error) {
+	v, ok := a[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func (a Attributes) Int(key string, def int64) (int64, error) {
+	v, ok := a[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func (a Attributes) Float(key string, def float64) (float64, error) {
+	v, ok := a[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func (a Attributes) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := a[key]
+	if !ok {
+		return def, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Sub 返回以 prefix 开头的属性，返回的 key 去掉了 prefix
+func (a Attributes) Sub(prefix string) Attributes {
+	var sub Attributes
+	for k, v := range a {
+		if strings.HasPrefix(k, prefix) {
+			if sub == nil {
+				sub = make(Attributes)
+			}
+			sub[k[len(prefix):]] = v
+		}
+	}
+	return sub
+}
+
+// MergeAttributes 合并 base 和 override，override 中的同名属性优先，两者都为空时返回 nil
+func MergeAttributes(base Attributes, override Attributes) Attributes {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(Attributes, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,47 @@
+package apimeta
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAttributes(t *testing.T) {
+	attrs := MergeAttributes(Attributes{
+		"cors.origins":   "https://a.com, https://b.com",
+		"cors.max-age":   "10m",
+		"body.max-bytes": "1024",
+		"feature.beta":   "true",
+	}, Attributes{
+		"body.max-bytes": "4096",
+		"auth.ratio":     "0.5",
+	})
+
+	if got := attrs.Strings("cors.origins"); !reflect.DeepEqual(got, []string{"https://a.com", "https://b.com"}) {
+		t.Errorf("Strings() = %v", got)
+	}
+	if got, err := attrs.Duration("cors.max-age", 0); got != 10*time.Minute || err != nil {
+		t.Errorf("Duration() = %v, %v", got, err)
+	}
+	if got, err := attrs.Int("body.max-bytes", 0); got != 4096 || err != nil {
+		t.Errorf("Int() = %v, %v", got, err)
+	}
+	if got, err := attrs.Float("auth.ratio", 0); got != 0.5 || err != nil {
+		t.Errorf("Float() = %v, %v", got, err)
+	}
+	if got, err := attrs.Bool("feature.beta", false); !got || err != nil {
+		t.Errorf("Bool() = %v, %v", got, err)
+	}
+	if got, err := attrs.Bool("feature.alpha", true); !got || err != nil {
+		t.Errorf("Bool() default = %v, %v", got, err)
+	}
+	if _, err := attrs.Int("cors.origins", 0); err == nil {
+		t.Error("Int() on string value should fail")
+	}
+	if got := attrs.Sub("cors."); !reflect.DeepEqual(got, Attributes{"origins": "https://a.com, https://b.com", "max-age": "10m"}) {
+		t.Errorf("Sub() = %v", got)
+	}
+	if MergeAttributes(nil, Attributes{}) != nil {
+		t.Error("MergeAttributes() of empty should be nil")
+	}
+}
@@ -0,0 +1,17 @@
+package apimeta
+
+import (
+	"github.com/vizee/gapi-plus/httpcache"
+)
+
+// CachePolicy 是路由的 HTTP 缓存策略，通过注释中的 `@cache` 声明，比如：
+//
+//	@cache public max-age=60 vary=Accept-Language vary-query=page,size etag
+//
+// 方法没有声明时使用服务的声明，方法可以用 `@cache no-store` 关闭服务的缓存策略。
+type CachePolicy = httpcache.Policy
+
+// ParseCachePolicy 解析 `@cache` 注解，语法见 httpcache.ParsePolicy
+func ParseCachePolicy(line string) (*CachePolicy, error) {
+	return httpcache.ParsePolicy(line)
+}
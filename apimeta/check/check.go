@@ -0,0 +1,212 @@
+package check
+
+import (
+	"errors"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	"github.com/vizee/gapi-plus/apimeta/internal/helpers"
+	"github.com/vizee/gapi-plus/apimeta/protodesc"
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"github.com/vizee/gapi-plus/proto/reflection"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Checker 按照网关的严格模式解析 gapi 文件，用于在启动或者单元测试中提前发现注解错误
+type Checker struct {
+	// TimeoutPolicy 和 StrictFields 应该与网关的配置保持一致
+	TimeoutPolicy *apimeta.TimeoutPolicy
+	StrictFields  bool
+}
+
+type CheckReport struct {
+	// Routes 是忽略错误后能够解析出来的路由
+	Routes      []*apimeta.Route
+	Diagnostics []apimeta.Diagnostic
+}
+
+func (r *CheckReport) HasErrors() bool {
+	for i := range r.Diagnostics {
+		if r.Diagnostics[i].Severity == apimeta.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err 合并所有错误级别的诊断信息，没有错误时返回 nil
+func (r *CheckReport) Err() error {
+	var errs []error
+	for i := range r.Diagnostics {
+		if r.Diagnostics[i].Severity == apimeta.SeverityError {
+			errs = append(errs, &r.Diagnostics[i])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Checker) newParser() *protodesc.Parser {
+	p := protodesc.NewParser()
+	p.TimeoutPolicy = c.TimeoutPolicy
+	p.StrictFields = c.StrictFields
+	return p
+}
+
+// Check 解析 fds 中的所有文件，见 CheckFiles
+func (c *Checker) Check(fds *descriptorpb.FileDescriptorSet) *CheckReport {
+	return c.CheckFiles(sortFiles(fds.File), nil)
+}
+
+// CheckFiles 按照网关的严格模式逐个解析服务和方法，每个方法的错误都会单独报告，另外会检查所有能解析的路由之间的冲突。
+// files 需要按照依赖关系排序，filter 不为 nil 时只检查 filter 返回 true 的文件中的服务，其他文件只提供消息定义。
+// CollectGapiFiles 不会收集没有 gapi.server 的服务和流式方法，这里也跳过它们
+func (c *Checker) CheckFiles(files []*descriptorpb.FileDescriptorProto, filter func(fd *descriptorpb.FileDescriptorProto) bool) *CheckReport {
+	report := &CheckReport{}
+	p := c.newParser()
+	// 先解析所有文件中的消息，服务在后面逐个解析
+	for _, fd := range files {
+		_, err := p.AddFileAPIRoutes(nil, &descriptorpb.FileDescriptorProto{
+			Name:        fd.Name,
+			Package:     fd.Package,
+			MessageType: fd.MessageType,
+		}, true)
+		if err != nil {
+			report.parseError(err, "", apimeta.SourceLocation{File: fd.GetName()})
+		}
+	}
+
+	for _, fd := range files {
+		if filter != nil && !filter(fd) {
+			continue
+		}
+		locs := helpers.NewSourceLocations(fd.SourceCodeInfo)
+		sourceOf := func(path []int32) apimeta.SourceLocation {
+			line, column, _ := locs.Get(path...)
+			return apimeta.SourceLocation{File: fd.GetName(), Line: line, Column: column}
+		}
+		for si, sd := range fd.Service {
+			if server, _ := proto.GetExtension(sd.Options, annotation.E_Server).(string); server == "" {
+				continue
+			}
+			serviceName := sd.GetName()
+			if fd.GetPackage() != "" {
+				serviceName = fd.GetPackage() + "." + serviceName
+			}
+			// 服务上的错误会导致所有方法失败，只报告一次
+			_, err := p.AddFileAPIRoutes(nil, serviceFile(fd, si, -1), false)
+			if err != nil {
+				report.parseError(err, serviceName, sourceOf(descriptor.ServicePath(int32(si))))
+				continue
+			}
+			for mi, md := range sd.Method {
+				if md.GetClientStreaming() || md.GetServerStreaming() {
+					continue
+				}
+				routes, err := p.AddFileAPIRoutes(nil, serviceFile(fd, si, mi), false)
+				if err != nil {
+					report.parseError(err, helpers.ConcatFullMethodName(serviceName, md.GetName()), sourceOf(descriptor.MethodPath(int32(si), int32(mi))))
+					continue
+				}
+				report.Routes = append(report.Routes, routes...)
+			}
+		}
+	}
+	report.Diagnostics = append(p.Diagnostics(), report.Diagnostics...)
+	report.Diagnostics = append(report.Diagnostics, apimeta.CheckConflicts(report.Routes)...)
+	return report
+}
+
+// parseError 记录解析器返回的错误，Diagnostic 类型的错误已经记录在解析器的 Diagnostics 中
+func (r *CheckReport) parseError(err error, method string, source apimeta.SourceLocation) {
+	var diag *apimeta.Diagnostic
+	if errors.As(err, &diag) {
+		return
+	}
+	r.Diagnostics = append(r.Diagnostics, apimeta.Diagnostic{
+		Severity: apimeta.SeverityError,
+		Method:   method,
+		Source:   source,
+		Message:  err.Error(),
+	})
+}
+
+// serviceFile 返回只包含 fd 中第 si 个服务的文件，mi 不小于 0 时服务只保留第 mi 个方法，
+// 注释的位置会随着服务和方法的下标一起调整，这样解析器能够逐个方法报告错误
+func serviceFile(fd *descriptorpb.FileDescriptorProto, si int, mi int) *descriptorpb.FileDescriptorProto {
+	sd := fd.Service[si]
+	svc := &descriptorpb.ServiceDescriptorProto{
+		Name:    sd.Name,
+		Options: sd.Options,
+	}
+	if mi >= 0 {
+		svc.Method = []*descriptorpb.MethodDescriptorProto{sd.Method[mi]}
+	}
+
+	info := &descriptorpb.SourceCodeInfo{}
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		path := loc.Path
+		if len(path) < 2 || path[0] != descriptor.FileServiceField || path[1] != int32(si) {
+			continue
+		}
+		if len(path) >= 4 && path[2] == descriptor.ServiceMethodField {
+			if path[3] != int32(mi) {
+				continue
+			}
+			path = append(descriptor.MethodPath(0, 0), path[4:]...)
+		} else {
+			path = append(descriptor.ServicePath(0), path[2:]...)
+		}
+		loc = proto.Clone(loc).(*descriptorpb.SourceCodeInfo_Location)
+		loc.Path = path
+		info.Location = append(info.Location, loc)
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:           fd.Name,
+		Package:        fd.Package,
+		Service:        []*descriptorpb.ServiceDescriptorProto{svc},
+		SourceCodeInfo: info,
+	}
+}
+
+// CheckServer 收集 srv 上的 gapi 文件并检查
+func (c *Checker) CheckServer(srv reflection.ServiceInfoProvider, opts ...reflection.Option) (*CheckReport, error) {
+	fds, err := reflection.CollectGapiFiles(srv, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Check(fds), nil
+}
+
+// SelfCheck 使用默认的 Checker 检查 srv 上的 gapi 文件
+func SelfCheck(srv reflection.ServiceInfoProvider, opts ...reflection.Option) (*CheckReport, error) {
+	return (&Checker{}).CheckServer(srv, opts...)
+}
+
+// sortFiles 把文件按照依赖关系排序，被依赖的文件在前，不在集合中的依赖会被忽略
+func sortFiles(files []*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(files))
+	for _, fd := range files {
+		byName[fd.GetName()] = fd
+	}
+	sorted := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
+	visit := make(map[string]bool, len(files))
+	var walk func(fd *descriptorpb.FileDescriptorProto)
+	walk = func(fd *descriptorpb.FileDescriptorProto) {
+		if visit[fd.GetName()] {
+			return
+		}
+		visit[fd.GetName()] = true
+		for _, dep := range fd.Dependency {
+			if dfd := byName[dep]; dfd != nil {
+				walk(dfd)
+			}
+		}
+		sorted = append(sorted, fd)
+	}
+	for _, fd := range files {
+		walk(fd)
+	}
+	return sorted
+}
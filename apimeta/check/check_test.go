@@ -0,0 +1,162 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/reflection"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func makeTestFile() *descriptorpb.FileDescriptorProto {
+	serviceOpts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(serviceOpts, annotation.E_Server, "check-server")
+	methodOpts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(methodOpts, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/call"}})
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("check/api.proto"),
+		Package:    proto.String("gapi.testdata.check"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"gapi/annotation.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Req"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+			},
+			{Name: proto.String("Resp")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name:    proto.String("ApiService"),
+				Options: serviceOpts,
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Call"),
+						InputType:  proto.String(".gapi.testdata.check.Req"),
+						OutputType: proto.String(".gapi.testdata.check.Resp"),
+						Options:    methodOpts,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(sd *descriptorpb.ServiceDescriptorProto)
+		routes int
+		errors []string
+	}{
+		{
+			name:   "no handler",
+			routes: 0,
+			errors: []string{"invalid method 'Call'"},
+		},
+		{
+			name: "ok",
+			modify: func(sd *descriptorpb.ServiceDescriptorProto) {
+				proto.SetExtension(sd.Options, annotation.E_DefaultHandler, "json")
+			},
+			routes: 1,
+		},
+		{
+			name: "bad middleware",
+			modify: func(sd *descriptorpb.ServiceDescriptorProto) {
+				proto.SetExtension(sd.Options, annotation.E_DefaultHandler, "json")
+				proto.SetExtension(sd.Options, annotation.E_Use, []string{"bad name"})
+			},
+			routes: 0,
+			errors: []string{"invalid middleware name 'bad name'"},
+		},
+		{
+			name: "errors per method",
+			modify: func(sd *descriptorpb.ServiceDescriptorProto) {
+				proto.SetExtension(sd.Options, annotation.E_DefaultHandler, "json")
+				for _, name := range []string{"BadA", "BadB"} {
+					bad := proto.Clone(sd.Method[0]).(*descriptorpb.MethodDescriptorProto)
+					bad.Name = proto.String(name)
+					proto.SetExtension(bad.Options, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/" + name}, Use: []string{"bad " + name}})
+					sd.Method = append(sd.Method, bad)
+				}
+			},
+			routes: 1,
+			errors: []string{"invalid middleware name 'bad BadA'", "invalid middleware name 'bad BadB'"},
+		},
+		{
+			name: "conflict",
+			modify: func(sd *descriptorpb.ServiceDescriptorProto) {
+				proto.SetExtension(sd.Options, annotation.E_DefaultHandler, "json")
+				dup := proto.Clone(sd.Method[0]).(*descriptorpb.MethodDescriptorProto)
+				dup.Name = proto.String("Dup")
+				proto.SetExtension(dup.Options, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/:action"}})
+				sd.Method = append(sd.Method, dup)
+			},
+			routes: 2,
+			errors: []string{"route POST /:action conflicts with /call"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := &descriptorpb.FileDescriptorSet{
+				File: []*descriptorpb.FileDescriptorProto{
+					protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+					protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+				},
+			}
+			api := makeTestFile()
+			if tt.modify != nil {
+				tt.modify(api.Service[0])
+			}
+			set.File = append(set.File, api)
+			files, err := protodesc.NewFiles(set)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := (&Checker{}).CheckServer(reflection.ServiceNames{"gapi.testdata.check.ApiService"}, reflection.WithResolver(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Routes) != tt.routes {
+				t.Errorf("routes = %d, want %d", len(report.Routes), tt.routes)
+			}
+			if report.HasErrors() != (len(tt.errors) > 0) {
+				t.Fatalf("HasErrors() = %v, diagnostics = %v", report.HasErrors(), report.Diagnostics)
+			}
+			if len(tt.errors) == 0 {
+				if report.Err() != nil {
+					t.Fatalf("Err() = %v", report.Err())
+				}
+				return
+			}
+			msg := report.Err().Error()
+			for _, want := range tt.errors {
+				if !strings.Contains(msg, want) {
+					t.Errorf("Err() = %q, want %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	files := []*descriptorpb.FileDescriptorProto{
+		{Name: proto.String("a.proto"), Dependency: []string{"b.proto", "external.proto"}},
+		{Name: proto.String("c.proto")},
+		{Name: proto.String("b.proto"), Dependency: []string{"c.proto"}},
+	}
+	var names []string
+	for _, fd := range sortFiles(files) {
+		names = append(names, fd.GetName())
+	}
+	if strings.Join(names, ",") != "c.proto,b.proto,a.proto" {
+		t.Fatalf("sortFiles() = %v", names)
+	}
+}
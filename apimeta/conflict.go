@@ -0,0 +1,56 @@
+package apimeta
+
+import (
+	"strings"
+)
+
+// pathsConflict 按照 httprouter 的规则判断两个路径能否同时注册：参数段（`:name`）与同一位置不同的段冲突，不论之后还有多少段，通配段（`*name`）与之后的所有段冲突
+func pathsConflict(a string, b string) bool {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+	for i := 0; ; i++ {
+		if i == len(as) || i == len(bs) {
+			return len(as) == len(bs)
+		}
+		x, y := as[i], bs[i]
+		if strings.HasPrefix(x, "*") || strings.HasPrefix(y, "*") {
+			return true
+		}
+		if x != y {
+			return strings.HasPrefix(x, ":") || strings.HasPrefix(y, ":")
+		}
+	}
+}
+
+// CheckConflicts 检查路由表中相同 HTTP 方法下路径冲突并且 Hosts 有交集的路由，每对冲突的路由报告一次，报告在后出现的路由上
+func CheckConflicts(routes []*Route) []Diagnostic {
+	var diags []Diagnostic
+	byMethod := make(map[string][]*Route)
+	for _, r := range routes {
+		method := strings.ToUpper(r.Method)
+		for _, prev := range byMethod[method] {
+			if !pathsConflict(prev.Path, r.Path) || !hostsOverlap(prev.Hosts, r.Hosts) {
+				continue
+			}
+			msg := "route " + method + " " + r.Path + " conflicts with " + prev.Path
+			if prev.Call != nil {
+				msg += " (" + prev.Call.Method
+				if loc := prev.Source.String(); loc != "" {
+					msg += " at " + loc
+				}
+				msg += ")"
+			}
+			diag := Diagnostic{
+				Severity: SeverityError,
+				Source:   r.Source,
+				Message:  msg,
+			}
+			if r.Call != nil {
+				diag.Method = r.Call.Method
+			}
+			diags = append(diags, diag)
+		}
+		byMethod[method] = append(byMethod[method], r)
+	}
+	return diags
+}
@@ -0,0 +1,72 @@
+package apimeta
+
+import (
+	"testing"
+
+	"github.com/vizee/gapi/metadata"
+)
+
+func TestPathsConflict(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "/user", b: "/user", want: true},
+		{a: "/user", b: "/order", want: false},
+		{a: "/user/:id", b: "/user/:uid", want: true},
+		{a: "/user/:id", b: "/user/new", want: true},
+		{a: "/user/:id", b: "/user/:id/orders", want: false},
+		{a: "/static/*path", b: "/static/js/app.js", want: true},
+		{a: "/user", b: "/user/", want: false},
+		{a: "/a/:id", b: "/a/b/c", want: true},
+		{a: "/a/b/c", b: "/a/:id", want: true},
+		{a: "/user/:id", b: "/user/:uid/orders", want: true},
+		{a: "/user/:id/orders", b: "/user/:id/items/:item", want: false},
+		{a: "/user/new", b: "/user/new/orders", want: false},
+		{a: "/static/*path", b: "/static", want: false},
+	}
+	for _, tt := range tests {
+		if got := pathsConflict(tt.a, tt.b); got != tt.want {
+			t.Errorf("pathsConflict(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	newRoute := func(method string, path string, hosts ...string) *Route {
+		return &Route{
+			Route: &metadata.Route{
+				Method: method,
+				Path:   path,
+				Call:   &metadata.Call{Method: "/svc/" + path},
+			},
+			Hosts: hosts,
+		}
+	}
+	tests := []struct {
+		name   string
+		routes []*Route
+		want   int
+	}{
+		{name: "different_methods", routes: []*Route{newRoute("GET", "/user"), newRoute("POST", "/user")}},
+		{name: "same_path", routes: []*Route{newRoute("GET", "/user"), newRoute("get", "/user")}, want: 1},
+		{name: "different_hosts", routes: []*Route{newRoute("GET", "/user", "admin.example.com"), newRoute("GET", "/user", "*.partner.example.com")}},
+		{name: "wildcard_host", routes: []*Route{newRoute("GET", "/user", "a.partner.example.com"), newRoute("GET", "/user", "*.partner.example.com")}, want: 1},
+		{name: "any_host", routes: []*Route{newRoute("GET", "/user", "admin.example.com"), newRoute("GET", "/user")}, want: 1},
+		{name: "longer_static", routes: []*Route{newRoute("GET", "/user/:id"), newRoute("GET", "/user/new/orders")}, want: 1},
+		{name: "three", routes: []*Route{newRoute("GET", "/user/:id"), newRoute("GET", "/user/new"), newRoute("GET", "/user/:uid")}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := CheckConflicts(tt.routes)
+			if len(diags) != tt.want {
+				t.Fatalf("CheckConflicts() = %v, want %d diagnostics", diags, tt.want)
+			}
+			for _, d := range diags {
+				if d.Severity != SeverityError || d.Method == "" {
+					t.Errorf("unexpected diagnostic %+v", d)
+				}
+			}
+		})
+	}
+}
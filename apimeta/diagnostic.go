@@ -0,0 +1,37 @@
+package apimeta
+
+type Severity uint8
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Diagnostic 描述路由解析过程中发现的问题，Severity 为 SeverityError 的问题会导致方法被拒绝
+type Diagnostic struct {
+	Severity Severity
+	Method   string
+	Source   SourceLocation
+	Message  string
+}
+
+func (d *Diagnostic) Error() string {
+	prefix := d.Source.String()
+	if prefix == "" {
+		prefix = d.Method
+	}
+	if prefix == "" {
+		return d.Message
+	}
+	return prefix + ": " + d.Message
+}
@@ -0,0 +1,151 @@
+package apimeta
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vizee/jsonpb"
+)
+
+const DefaultFieldMaskParam = "fields"
+
+// maskNode 是字段掩码解析后的树，nil 表示选中整个字段
+type maskNode map[string]maskNode
+
+func parseFieldMask(mask string) (maskNode, string) {
+	paths := strings.Split(mask, ",")
+	n := 0
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			paths[n] = path
+			n++
+		}
+	}
+	paths = paths[:n]
+	sort.Strings(paths)
+
+	root := make(maskNode)
+	for _, path := range paths {
+		node := root
+		for {
+			dot := strings.IndexByte(path, '.')
+			if dot < 0 {
+				// 选中整个字段时覆盖已有的子路径
+				node[path] = nil
+				break
+			}
+			name := path[:dot]
+			child, ok := node[name]
+			if ok && child == nil {
+				break
+			}
+			if child == nil {
+				child = make(maskNode)
+				node[name] = child
+			}
+			node = child
+			path = path[dot+1:]
+		}
+	}
+	return root, strings.Join(paths, ",")
+}
+
+func projectMessage(msg *jsonpb.Message, mask maskNode, prefix string) (*jsonpb.Message, error) {
+	for name := range mask {
+		if msg.FieldByName(name) == nil {
+			return nil, errors.New("unknown field '" + prefix + name + "' in field mask")
+		}
+	}
+
+	fields := make([]jsonpb.Field, len(msg.Fields))
+	copy(fields, msg.Fields)
+	for i := range fields {
+		field := &fields[i]
+		sub, ok := mask[field.Name]
+		if !ok {
+			field.Omit = jsonpb.OmitAlways
+			continue
+		}
+		if sub == nil {
+			continue
+		}
+		if field.Kind != jsonpb.MessageKind || field.Ref == nil {
+			return nil, errors.New("field '" + prefix + field.Name + "' is not a message")
+		}
+		ref, err := projectMessage(field.Ref, sub, prefix+field.Name+".")
+		if err != nil {
+			return nil, err
+		}
+		field.Ref = ref
+	}
+	return jsonpb.NewMessage(msg.Name, fields, true, true), nil
+}
+
+// ProjectMessage 返回只保留 mask 中字段的 msg 副本，mask 是逗号分隔的 JSON 字段路径，比如 `id,name,who.name`。
+// 未选中的字段被标记为 jsonpb.OmitAlways，mask 中包含未知字段时返回错误。
+func ProjectMessage(msg *jsonpb.Message, mask string) (*jsonpb.Message, error) {
+	node, _ := parseFieldMask(mask)
+	if len(node) == 0 {
+		return msg, nil
+	}
+	return projectMessage(msg, node, "")
+}
+
+type projectionKey struct {
+	msg  *jsonpb.Message
+	mask string
+}
+
+// Projector 缓存按照字段掩码裁剪后的输出消息，路径顺序不同的等价掩码共享同一个缓存项
+type Projector struct {
+	maxEntries int
+	mu         sync.RWMutex
+	cache      map[projectionKey]*jsonpb.Message
+}
+
+// NewProjector 创建 Projector，缓存项超过 maxEntries 时清空缓存，maxEntries <= 0 表示不限制
+func NewProjector(maxEntries int) *Projector {
+	return &Projector{
+		maxEntries: maxEntries,
+		cache:      make(map[projectionKey]*jsonpb.Message),
+	}
+}
+
+func (p *Projector) Project(msg *jsonpb.Message, mask string) (*jsonpb.Message, error) {
+	node, canonical := parseFieldMask(mask)
+	if len(node) == 0 {
+		return msg, nil
+	}
+
+	key := projectionKey{msg: msg, mask: canonical}
+	p.mu.RLock()
+	projected := p.cache[key]
+	p.mu.RUnlock()
+	if projected != nil {
+		return projected, nil
+	}
+
+	projected, err := projectMessage(msg, node, "")
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if p.maxEntries > 0 && len(p.cache) >= p.maxEntries {
+		p.cache = make(map[projectionKey]*jsonpb.Message)
+	}
+	p.cache[key] = projected
+	p.mu.Unlock()
+	return projected, nil
+}
+
+// ProjectRoute 返回路由按照 mask 裁剪后的输出消息，路由没有开启 FieldMask 时返回错误
+func (p *Projector) ProjectRoute(route *Route, mask string) (*jsonpb.Message, error) {
+	if route.FieldMask == "" {
+		return nil, errors.New("field mask is not enabled on " + route.Method + " " + route.Path)
+	}
+	return p.Project(route.Call.Out, mask)
+}
@@ -0,0 +1,88 @@
+package apimeta
+
+import (
+	"testing"
+
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+)
+
+func newFieldMaskTestMessage() *jsonpb.Message {
+	user := jsonpb.NewMessage("pkg.User", []jsonpb.Field{
+		{Name: "name", Kind: jsonpb.StringKind, Tag: 1},
+		{Name: "age", Kind: jsonpb.Int32Kind, Tag: 2},
+	}, true, true)
+	return jsonpb.NewMessage("pkg.Response", []jsonpb.Field{
+		{Name: "id", Kind: jsonpb.Int64Kind, Tag: 1},
+		{Name: "name", Kind: jsonpb.StringKind, Tag: 2},
+		{Name: "who", Kind: jsonpb.MessageKind, Ref: user, Tag: 3},
+		{Name: "mentions", Kind: jsonpb.MessageKind, Ref: user, Tag: 4, Repeated: true},
+	}, true, true)
+}
+
+func TestProjectMessage(t *testing.T) {
+	msg := newFieldMaskTestMessage()
+
+	got, err := ProjectMessage(msg, "id, who.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	omitted := func(m *jsonpb.Message, name string) bool {
+		return m.FieldByName(name).Omit == jsonpb.OmitAlways
+	}
+	if omitted(got, "id") || !omitted(got, "name") || omitted(got, "who") || !omitted(got, "mentions") {
+		t.Fatalf("unexpected fields %+v", got.Fields)
+	}
+	who := got.FieldByName("who").Ref
+	if who == msg.FieldByName("who").Ref || omitted(who, "name") || !omitted(who, "age") {
+		t.Fatalf("unexpected nested fields %+v", who.Fields)
+	}
+	if omitted(msg, "name") {
+		t.Fatal("source message is modified")
+	}
+
+	got, err = ProjectMessage(msg, "who.name,who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FieldByName("who").Ref != msg.FieldByName("who").Ref {
+		t.Fatal("whole field should keep original message")
+	}
+
+	for _, mask := range []string{"unknown", "who.unknown", "id.value"} {
+		_, err = ProjectMessage(msg, mask)
+		if err == nil {
+			t.Fatalf("expect error for mask %q", mask)
+		}
+	}
+}
+
+func TestProjector(t *testing.T) {
+	msg := newFieldMaskTestMessage()
+	route := &Route{
+		Route:     &metadata.Route{Call: &metadata.Call{Out: msg}},
+		FieldMask: DefaultFieldMaskParam,
+	}
+
+	p := NewProjector(2)
+	a, err := p.ProjectRoute(route, "id,name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.ProjectRoute(route, "name, id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("equivalent masks should share the cached message")
+	}
+	if c, _ := p.ProjectRoute(route, ""); c != msg {
+		t.Fatal("empty mask should return original message")
+	}
+
+	route.FieldMask = ""
+	_, err = p.ProjectRoute(route, "id")
+	if err == nil {
+		t.Fatal("expect error when field mask is disabled")
+	}
+}
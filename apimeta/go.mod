@@ -3,9 +3,20 @@ module github.com/vizee/gapi-plus/apimeta
 go 1.20
 
 require (
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/vizee/gapi v0.4.0
-	github.com/vizee/gapi-plus/proto v0.2.1
+	github.com/vizee/gapi-plus/httpcache v0.3.0
+	github.com/vizee/gapi-plus/proto v0.3.0
 	github.com/vizee/gapi-proto-go v0.0.0-20230505112701-bca324ad1c1e
 	github.com/vizee/jsonpb v0.2.0
+	google.golang.org/grpc v1.55.0
 	google.golang.org/protobuf v1.30.0
 )
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
+)
@@ -0,0 +1,63 @@
+package apimeta
+
+type ServiceRoutes struct {
+	*Service
+	Routes []*Route
+}
+
+// ServerRoutes 是同一个 server 的所有路由，Files 是这些路由所在的源文件
+type ServerRoutes struct {
+	Name     string
+	Files    []string
+	Services []*ServiceRoutes
+}
+
+// serviceKey 同时包含 server，Service 为 nil 的路由按照 server 分别分组
+type serviceKey struct {
+	server  string
+	service *Service
+}
+
+// GroupRoutes 按照 server 和服务对路由分组，分组的顺序与路由第一次出现的顺序一致
+func GroupRoutes(routes []*Route) []*ServerRoutes {
+	var (
+		servers     []*ServerRoutes
+		serverIndex = make(map[string]*ServerRoutes)
+		svcIndex    = make(map[serviceKey]*ServiceRoutes)
+	)
+	for _, r := range routes {
+		server := serverIndex[r.Call.Server]
+		if server == nil {
+			server = &ServerRoutes{
+				Name: r.Call.Server,
+			}
+			serverIndex[server.Name] = server
+			servers = append(servers, server)
+		}
+
+		key := serviceKey{server: server.Name, service: r.Service}
+		svc := svcIndex[key]
+		if svc == nil {
+			svc = &ServiceRoutes{
+				Service: r.Service,
+			}
+			svcIndex[key] = svc
+			server.Services = append(server.Services, svc)
+
+			if r.Service != nil && !containsString(server.Files, r.Service.File) {
+				server.Files = append(server.Files, r.Service.File)
+			}
+		}
+		svc.Routes = append(svc.Routes, r)
+	}
+	return servers
+}
+
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
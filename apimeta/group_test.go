@@ -0,0 +1,64 @@
+package apimeta
+
+import (
+	"testing"
+
+	"github.com/vizee/gapi/metadata"
+)
+
+func TestGroupRoutes(t *testing.T) {
+	user := &Service{Name: "pkg.UserService", File: "user.proto", Server: "user-server", PathPrefix: "/user"}
+	admin := &Service{Name: "pkg.AdminService", File: "admin.proto", Server: "user-server"}
+	order := &Service{Name: "pkg.OrderService", File: "order.proto", Server: "order-server"}
+	newRoute := func(svc *Service, path string) *Route {
+		return &Route{
+			Route:   &metadata.Route{Method: "GET", Path: path, Call: &metadata.Call{Server: svc.Server}},
+			Service: svc,
+		}
+	}
+	routes := []*Route{
+		newRoute(user, "/user/get"),
+		newRoute(order, "/order/get"),
+		newRoute(admin, "/admin/ban"),
+		newRoute(user, "/user/list"),
+	}
+
+	servers := GroupRoutes(routes)
+	if len(servers) != 2 || servers[0].Name != "user-server" || servers[1].Name != "order-server" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	us := servers[0]
+	if len(us.Services) != 2 || us.Services[0].Service != user || len(us.Services[0].Routes) != 2 || us.Services[0].PathPrefix != "/user" {
+		t.Fatalf("unexpected services %+v", us.Services)
+	}
+	if len(us.Files) != 2 || us.Files[0] != "user.proto" || us.Files[1] != "admin.proto" {
+		t.Fatalf("unexpected files %v", us.Files)
+	}
+}
+
+func TestGroupRoutesWithoutService(t *testing.T) {
+	newRoute := func(server string, path string) *Route {
+		return &Route{
+			Route: &metadata.Route{Method: "GET", Path: path, Call: &metadata.Call{Server: server}},
+		}
+	}
+	servers := GroupRoutes([]*Route{
+		newRoute("a-server", "/a/1"),
+		newRoute("b-server", "/b/1"),
+		newRoute("a-server", "/a/2"),
+	})
+	if len(servers) != 2 {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	for i, want := range []int{2, 1} {
+		s := servers[i]
+		if len(s.Services) != 1 || s.Services[0].Service != nil || len(s.Services[0].Routes) != want || len(s.Files) != 0 {
+			t.Fatalf("unexpected services of %s: %+v", s.Name, s.Services)
+		}
+		for _, r := range s.Services[0].Routes {
+			if r.Call.Server != s.Name {
+				t.Errorf("route %s grouped into %s", r.Path, s.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package apimeta
+
+import (
+	"net"
+	"strings"
+)
+
+// NormalHost 去掉 host 中的端口并转换成小写
+func NormalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// MatchHostPattern 判断 host 是否匹配 pattern，`*.example.com` 匹配 example.com 的任意一级或多级子域名，但是不匹配 example.com 本身
+func MatchHostPattern(pattern string, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:]
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return pattern == host
+}
+
+// hostPatternsOverlap 判断两个 host 模式是否可能匹配同一个 host
+func hostPatternsOverlap(a string, b string) bool {
+	aw := strings.HasPrefix(a, "*.")
+	bw := strings.HasPrefix(b, "*.")
+	switch {
+	case aw && bw:
+		return strings.HasSuffix(a[1:], b[1:]) || strings.HasSuffix(b[1:], a[1:])
+	case aw:
+		return MatchHostPattern(a, b)
+	case bw:
+		return MatchHostPattern(b, a)
+	}
+	return a == b
+}
+
+// hostsOverlap 判断两组 host 模式是否有交集，空的一组表示匹配所有 host
+func hostsOverlap(a []string, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if hostPatternsOverlap(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchHost 判断路由能否处理发往 host 的请求，没有限制 Hosts 的路由匹配所有 host
+func (r *Route) MatchHost(host string) bool {
+	if len(r.Hosts) == 0 {
+		return true
+	}
+	host = NormalHost(host)
+	for _, pattern := range r.Hosts {
+		if MatchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
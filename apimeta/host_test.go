@@ -0,0 +1,51 @@
+package apimeta
+
+import (
+	"testing"
+)
+
+func TestRouteMatchHost(t *testing.T) {
+	route := &Route{Hosts: []string{"admin.example.com", "*.partner.example.com"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "admin.example.com", want: true},
+		{host: "Admin.Example.com:8080", want: true},
+		{host: "a.partner.example.com", want: true},
+		{host: "a.b.partner.example.com", want: true},
+		{host: "partner.example.com", want: false},
+		{host: "xpartner.example.com", want: false},
+		{host: "example.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := route.MatchHost(tt.host); got != tt.want {
+				t.Errorf("MatchHost(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+	if !(&Route{}).MatchHost("any.example.com") {
+		t.Error("route without hosts should match all hosts")
+	}
+}
+
+func TestHostPatternsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "a.example.com", b: "a.example.com", want: true},
+		{a: "a.example.com", b: "b.example.com", want: false},
+		{a: "*.example.com", b: "a.example.com", want: true},
+		{a: "a.example.com", b: "*.example.com", want: true},
+		{a: "*.example.com", b: "example.com", want: false},
+		{a: "*.example.com", b: "*.a.example.com", want: true},
+		{a: "*.a.example.com", b: "*.b.example.com", want: false},
+	}
+	for _, tt := range tests {
+		if got := hostPatternsOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("hostPatternsOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
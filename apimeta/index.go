@@ -0,0 +1,128 @@
+package apimeta
+
+import (
+	"sync"
+
+	"github.com/vizee/gapi/metadata"
+)
+
+// RouteRef 是反向索引中指向 HTTP 路由的一项
+type RouteRef struct {
+	Method string
+	Path   string
+	Server string
+}
+
+// MethodIndex 是从 gRPC 完整方法名（`/pkg.Service/Method`）到 HTTP 路由的反向索引，用于把按 gRPC 方法记录的日志和链路与 HTTP 路径关联起来。
+// 索引以 server 为单位更新：SetServer 替换一个 server 的全部路由，RemoveServer 删除它的路由，其他 server 不受影响。
+// 调用方在某个 server 的路由发生变化时传入该 server 完整的新路由即可，所有方法都可以并发调用。
+type MethodIndex struct {
+	mu      sync.RWMutex
+	methods map[string][]RouteRef
+	servers map[string][]string
+}
+
+func NewMethodIndex() *MethodIndex {
+	return &MethodIndex{
+		methods: make(map[string][]RouteRef),
+		servers: make(map[string][]string),
+	}
+}
+
+// BuildMethodIndex 从完整的路由表创建索引
+func BuildMethodIndex(routes []*metadata.Route) *MethodIndex {
+	idx := NewMethodIndex()
+	byServer := make(map[string][]*metadata.Route)
+	var servers []string
+	for _, r := range routes {
+		if _, ok := byServer[r.Call.Server]; !ok {
+			servers = append(servers, r.Call.Server)
+		}
+		byServer[r.Call.Server] = append(byServer[r.Call.Server], r)
+	}
+	for _, server := range servers {
+		idx.SetServer(server, byServer[server])
+	}
+	return idx
+}
+
+func normalFullMethod(method string) string {
+	if len(method) > 0 && method[0] != '/' {
+		return "/" + method
+	}
+	return method
+}
+
+func (idx *MethodIndex) removeServer(server string) {
+	for _, method := range idx.servers[server] {
+		refs := idx.methods[method]
+		kept := refs[:0]
+		for _, ref := range refs {
+			if ref.Server != server {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.methods, method)
+		} else {
+			idx.methods[method] = kept
+		}
+	}
+	delete(idx.servers, server)
+}
+
+// SetServer 用 routes 替换 server 原有的全部路由，routes 中不属于 server 的路由会被忽略
+func (idx *MethodIndex) SetServer(server string, routes []*metadata.Route) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeServer(server)
+
+	var methods []string
+	for _, r := range routes {
+		if r.Call.Server != server {
+			continue
+		}
+		method := normalFullMethod(r.Call.Method)
+		refs, found := idx.methods[method]
+		if !found || !containsServer(refs, server) {
+			methods = append(methods, method)
+		}
+		idx.methods[method] = append(refs, RouteRef{
+			Method: r.Method,
+			Path:   r.Path,
+			Server: server,
+		})
+	}
+	if len(methods) > 0 {
+		idx.servers[server] = methods
+	}
+}
+
+func (idx *MethodIndex) RemoveServer(server string) {
+	idx.mu.Lock()
+	idx.removeServer(server)
+	idx.mu.Unlock()
+}
+
+// Lookup 返回 fullMethod 对应的所有路由，fullMethod 可以省略开头的 '/'
+func (idx *MethodIndex) Lookup(fullMethod string) []RouteRef {
+	idx.mu.RLock()
+	refs := idx.methods[normalFullMethod(fullMethod)]
+	var result []RouteRef
+	if len(refs) > 0 {
+		result = make([]RouteRef, len(refs))
+		copy(result, refs)
+	}
+	idx.mu.RUnlock()
+	return result
+}
+
+func containsServer(refs []RouteRef, server string) bool {
+	for i := range refs {
+		if refs[i].Server == server {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package apimeta
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vizee/gapi/metadata"
+)
+
+func newMetadataRoute(server string, method string, httpMethod string, path string) *metadata.Route {
+	return &metadata.Route{
+		Method: httpMethod,
+		Path:   path,
+		Call: &metadata.Call{
+			Server: server,
+			Method: method,
+		},
+	}
+}
+
+func TestMethodIndex(t *testing.T) {
+	idx := BuildMethodIndex([]*metadata.Route{
+		newMetadataRoute("user", "/user.UserService/Get", "GET", "/user"),
+		newMetadataRoute("user", "/user.UserService/Get", "GET", "/v2/user"),
+		newMetadataRoute("order", "/order.OrderService/Create", "POST", "/order"),
+		newMetadataRoute("legacy", "/user.UserService/Get", "GET", "/legacy/user"),
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		want   []RouteRef
+	}{
+		{
+			name:   "multiple",
+			method: "/user.UserService/Get",
+			want: []RouteRef{
+				{Method: "GET", Path: "/user", Server: "user"},
+				{Method: "GET", Path: "/v2/user", Server: "user"},
+				{Method: "GET", Path: "/legacy/user", Server: "legacy"},
+			},
+		},
+		{name: "without_slash", method: "order.OrderService/Create", want: []RouteRef{{Method: "POST", Path: "/order", Server: "order"}}},
+		{name: "not_found", method: "/order.OrderService/Delete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.Lookup(tt.method); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	idx.SetServer("user", []*metadata.Route{
+		newMetadataRoute("user", "/user.UserService/Update", "PUT", "/user"),
+	})
+	if got := idx.Lookup("/user.UserService/Get"); !reflect.DeepEqual(got, []RouteRef{{Method: "GET", Path: "/legacy/user", Server: "legacy"}}) {
+		t.Errorf("Lookup() after SetServer = %v", got)
+	}
+	if got := idx.Lookup("/user.UserService/Update"); len(got) != 1 || got[0].Method != "PUT" {
+		t.Errorf("Lookup() after SetServer = %v", got)
+	}
+
+	idx.RemoveServer("legacy")
+	idx.RemoveServer("order")
+	if got := idx.Lookup("/user.UserService/Get"); got != nil {
+		t.Errorf("Lookup() after RemoveServer = %v", got)
+	}
+	if len(idx.methods) != 1 || len(idx.servers) != 1 {
+		t.Errorf("index not cleaned: %v %v", idx.methods, idx.servers)
+	}
+}
@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// Annotations 是注释中 `@name value` 形式的注解，同名注解的多行内容会合并到一起。
+// 解析规则与 protoc-gen-gapi-swagger 保持一致：第一个注解之前的文本会被忽略，不以 @ 开头的行归属于上一个注解。
+type Annotations map[string][]string
+
+func ExtractAnnotations(comments string) Annotations {
+	comments = strings.TrimSpace(comments)
+	if comments == "" {
+		return nil
+	}
+
+	var (
+		ans  Annotations
+		curr string
+	)
+	for _, line := range strings.Split(comments, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "@") {
+			sp := strings.IndexByte(line, ' ')
+			if sp < 0 {
+				sp = len(line)
+			}
+			curr = strings.ToLower(line[1:sp])
+			line = strings.TrimSpace(line[sp:])
+		}
+		if curr == "" {
+			continue
+		}
+
+		if ans == nil {
+			ans = make(Annotations)
+		}
+		ans[curr] = append(ans[curr], line)
+	}
+	return ans
+}
+
+func (ans Annotations) Has(name string) bool {
+	_, ok := ans[name]
+	return ok
+}
+
+func (ans Annotations) Lines(name string) []string {
+	return ans[name]
+}
+
+func (ans Annotations) Text(name string) string {
+	return strings.Join(ans[name], "\n")
+}
+
+func (ans Annotations) Line(name string) string {
+	return strings.Join(ans[name], " ")
+}
+
+// SplitList 按 sep 切分并去掉空白项
+func SplitList(s string, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
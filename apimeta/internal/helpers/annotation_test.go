@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments string
+		want     Annotations
+	}{
+		{name: "empty", comments: "  \n "},
+		{name: "no_annotation", comments: "Add 加法"},
+		{name: "simple", comments: "Add 加法\n@summary Add\n@deprecated\n", want: Annotations{"summary": {"Add"}, "deprecated": {""}}},
+		{name: "multiline", comments: "@description line1\nline2\n@TAGS a, b\n@tags c", want: Annotations{"description": {"line1", "line2"}, "tags": {"a, b", "c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractAnnotations(tt.comments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractAnnotations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,24 +17,6 @@ func ConcatFullMethodName(serviceName string, methodName string) string {
 	return s.String()
 }
 
-func CheckMiddlewareName(name string) bool {
-	if name == "" {
-		return false
-	}
-
-	for i := 0; i < len(name); i++ {
-		c := name[i]
-		if 'a' <= c && c <= 'z' ||
-			'A' <= c && c <= 'Z' ||
-			'0' <= c && c <= '9' ||
-			c == '_' || c == '-' {
-			continue
-		}
-		return false
-	}
-	return true
-}
-
 func GetTypeKind(ty descriptorpb.FieldDescriptorProto_Type) (jsonpb.Kind, bool) {
 	switch ty {
 	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
@@ -0,0 +1,396 @@
+package helpers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	"github.com/vizee/gapi-plus/apimeta/internal/slices"
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+	"google.golang.org/grpc/codes"
+)
+
+func parseSunset(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// FillRouteAnnotations 从 service 和 method 的注解中提取路由文档和选项，文档字段的含义与 protoc-gen-gapi-swagger 一致
+func FillRouteAnnotations(route *apimeta.Route, serviceAns Annotations, methodAns Annotations) error {
+	route.Summary = methodAns.Text("summary")
+	route.Description = methodAns.Text("description")
+	route.Tags = slices.Merge(SplitList(methodAns.Line("tags"), ","), SplitList(serviceAns.Line("tags"), ","))
+	route.Deprecated = methodAns.Has("deprecated")
+	if sunset := methodAns.Line("sunset"); sunset != "" {
+		t, err := parseSunset(sunset)
+		if err != nil {
+			return errors.New("invalid sunset '" + sunset + "'")
+		}
+		route.Sunset = t
+	}
+	handlers := methodAns.Lines("handler")
+	if len(handlers) == 0 {
+		handlers = serviceAns.Lines("handler")
+	}
+	if len(handlers) > 0 {
+		route.Handlers = make([]apimeta.HandlerBinding, 0, len(handlers))
+		for _, line := range handlers {
+			h, err := parseHandlerBinding(line)
+			if err != nil {
+				return err
+			}
+			route.Handlers = append(route.Handlers, h)
+		}
+	}
+	cache := methodAns.Line("cache")
+	if !methodAns.Has("cache") {
+		cache = serviceAns.Line("cache")
+	}
+	if cache != "" {
+		policy, err := apimeta.ParseCachePolicy(cache)
+		if err != nil {
+			return err
+		}
+		route.Cache = policy
+	}
+	retry := methodAns.Line("retry")
+	if !methodAns.Has("retry") {
+		retry = serviceAns.Line("retry")
+	}
+	if retry != "" && retry != "off" {
+		policy, err := parseRetryPolicy(retry)
+		if err != nil {
+			return err
+		}
+		route.Retry = policy
+	}
+	if methodAns.Has("host") {
+		hosts, err := ParseHosts(methodAns)
+		if err != nil {
+			return err
+		}
+		route.Hosts = hosts
+	} else if route.Service != nil {
+		route.Hosts = route.Service.Hosts
+	}
+	attrs, err := ParseAttributes(methodAns)
+	if err != nil {
+		return err
+	}
+	if route.Service != nil {
+		route.Attributes = apimeta.MergeAttributes(route.Service.Attributes, attrs)
+	} else {
+		route.Attributes = attrs
+	}
+	if methodAns.Has("fieldmask") {
+		route.FieldMask = methodAns.Line("fieldmask")
+		if route.FieldMask == "" {
+			route.FieldMask = apimeta.DefaultFieldMaskParam
+		}
+	}
+	return nil
+}
+
+func checkMediaType(s string) bool {
+	t, sub, ok := strings.Cut(s, "/")
+	return ok && t != "" && sub != "" && !strings.Contains(sub, "/") && (t != "*" || sub == "*")
+}
+
+// parseHandlerBinding 解析 `<consumes> <produces> <handler>` 形式的 @handler 注解
+func parseHandlerBinding(line string) (apimeta.HandlerBinding, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return apimeta.HandlerBinding{}, errors.New("invalid handler binding '" + line + "'")
+	}
+	h := apimeta.HandlerBinding{
+		Consumes: strings.ToLower(fields[0]),
+		Produces: strings.ToLower(fields[1]),
+		Handler:  fields[2],
+	}
+	if !checkMediaType(h.Consumes) || !checkMediaType(h.Produces) || !apimeta.CheckMiddlewareName(h.Handler) {
+		return apimeta.HandlerBinding{}, errors.New("invalid handler binding '" + line + "'")
+	}
+	return h, nil
+}
+
+func parseStatusCodes(s string) ([]codes.Code, error) {
+	names := SplitList(s, ",")
+	cs := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		var c codes.Code
+		err := c.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name))))
+		if err != nil || c == codes.OK {
+			return nil, errors.New("invalid status code '" + name + "'")
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// parseRetryPolicy 解析 @retry 注解，没有指定的参数使用默认值，codes 默认为 UNAVAILABLE
+func parseRetryPolicy(line string) (*apimeta.RetryPolicy, error) {
+	policy := &apimeta.RetryPolicy{
+		InitialBackoff:    apimeta.DefaultRetryBackoff,
+		MaxBackoff:        apimeta.DefaultRetryMaxBackoff,
+		BackoffMultiplier: apimeta.DefaultRetryMultiplier,
+	}
+	for _, item := range strings.Fields(line) {
+		key, value, _ := strings.Cut(item, "=")
+		var err error
+		switch strings.ToLower(key) {
+		case "attempts":
+			policy.MaxAttempts, err = strconv.Atoi(value)
+		case "backoff":
+			policy.InitialBackoff, err = time.ParseDuration(value)
+		case "max-backoff":
+			policy.MaxBackoff, err = time.ParseDuration(value)
+		case "multiplier":
+			policy.BackoffMultiplier, err = strconv.ParseFloat(value, 64)
+		case "hedging-delay":
+			policy.HedgingDelay, err = time.ParseDuration(value)
+		case "codes":
+			policy.Codes, err = parseStatusCodes(value)
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return nil, errors.New("invalid retry option '" + item + "'")
+		}
+	}
+	if len(policy.Codes) == 0 {
+		policy.Codes = []codes.Code{codes.Unavailable}
+	}
+	if policy.MaxAttempts < 2 || policy.InitialBackoff <= 0 || policy.MaxBackoff < policy.InitialBackoff || policy.BackoffMultiplier < 1 || policy.HedgingDelay < 0 {
+		return nil, errors.New("invalid retry policy '" + line + "'")
+	}
+	return policy, nil
+}
+
+func checkAttributeKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'a' <= c && c <= 'z' ||
+			'A' <= c && c <= 'Z' ||
+			'0' <= c && c <= '9' ||
+			c == '_' || c == '-' || c == '.' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func checkHostPattern(pattern string) bool {
+	pattern = strings.TrimPrefix(pattern, "*.")
+	if pattern == "" {
+		return false
+	}
+	for _, label := range strings.Split(pattern, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' {
+				continue
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// ParseHosts 解析 `@host` 注解，多个 host 模式用 ',' 分隔，通配符只能作为第一级出现
+func ParseHosts(ans Annotations) ([]string, error) {
+	hosts := SplitList(strings.ToLower(ans.Line("host")), ",")
+	for _, host := range hosts {
+		if !checkHostPattern(host) {
+			return nil, errors.New("invalid host pattern '" + host + "'")
+		}
+	}
+	return hosts, nil
+}
+
+// ParseAttributes 解析 `@attr key=value` 注解，每行一个属性
+func ParseAttributes(ans Annotations) (apimeta.Attributes, error) {
+	lines := ans.Lines("attr")
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	attrs := make(apimeta.Attributes, len(lines))
+	for _, line := range lines {
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !checkAttributeKey(key) {
+			return nil, errors.New("invalid attribute '" + line + "'")
+		}
+		if found {
+			attrs[key] = strings.TrimSpace(value)
+		} else {
+			attrs[key] = "true"
+		}
+	}
+	return attrs, nil
+}
+
+// ResolveTimeout 把毫秒单位的 timeout 转换成 time.Duration，policy 不为 nil 时应用超时策略，负数的 timeout 总是被拒绝
+func ResolveTimeout(policy *apimeta.TimeoutPolicy, server string, method string, source apimeta.SourceLocation, timeout int64) (time.Duration, *apimeta.Diagnostic) {
+	d := time.Duration(timeout) * time.Millisecond
+	var diag *apimeta.Diagnostic
+	if d < 0 {
+		diag = &apimeta.Diagnostic{
+			Severity: apimeta.SeverityError,
+			Message:  "negative timeout " + d.String(),
+		}
+		d = 0
+	} else if policy != nil {
+		d, diag = policy.Apply(server, d)
+	}
+	if diag != nil {
+		diag.Method = method
+		diag.Source = source
+	}
+	return d, diag
+}
+
+func isIntegerKind(kind jsonpb.Kind) bool {
+	return jsonpb.IsNumericKind(kind) && kind != jsonpb.DoubleKind && kind != jsonpb.FloatKind
+}
+
+// ResolveResponseBindings 把输出消息中绑定到 header 的字段转换成响应绑定，其他绑定只在消息作为输入时有意义，这里忽略
+func ResolveResponseBindings(bindings []Binding) ([]apimeta.ResponseBinding, error) {
+	var respBindings []apimeta.ResponseBinding
+	hasStatus := false
+	for _, b := range bindings {
+		if b.Bind != metadata.BindHeader {
+			continue
+		}
+		rb := apimeta.ResponseBinding{
+			Name:   b.Name,
+			Kind:   b.Kind,
+			Tag:    b.Tag,
+			Target: apimeta.ResponseHeader,
+		}
+		if b.Name == apimeta.StatusBindingName {
+			if b.Repeated || !isIntegerKind(b.Kind) || hasStatus {
+				return nil, errors.New("invalid status binding on output field tag " + strconv.Itoa(int(b.Tag)))
+			}
+			rb.Target = apimeta.ResponseStatus
+			hasStatus = true
+		} else if b.Repeated || b.Kind == jsonpb.MessageKind || b.Kind == jsonpb.MapKind {
+			return nil, errors.New("output field '" + b.Name + "' bound to header must be scalar")
+		}
+		respBindings = append(respBindings, rb)
+	}
+	return respBindings, nil
+}
+
+// ResolveRequestBindings 把输入消息的 Bindings 转换成请求参数绑定，绑定到 query 的消息类型字段会递归展开成标量字段的绑定。
+// call 是可以直接用于 metadata.Call 的部分，即所有顶层标量字段，其中 repeated 字段在 metadata.Call 中只会绑定第一个值；
+// nested 是无法用 metadata.Call 表示的消息类型字段名，调用方应该报告诊断信息。
+func ResolveRequestBindings(bindings []Binding) (all []apimeta.FieldBinding, call []metadata.FieldBinding, nested []string, err error) {
+	if len(bindings) == 0 {
+		return nil, nil, nil, nil
+	}
+	all = make([]apimeta.FieldBinding, 0, len(bindings))
+	for i := range bindings {
+		b := &bindings[i]
+		switch {
+		case b.Kind == jsonpb.MapKind:
+			return nil, nil, nil, errors.New("map field '" + b.Name + "' can not be bound")
+		case b.Kind == jsonpb.MessageKind:
+			if b.Bind != metadata.BindQuery || b.Repeated {
+				return nil, nil, nil, errors.New("message field '" + b.Name + "' can only be bound to query")
+			}
+			all, err = expandBinding(all, b.Name, []uint32{b.Tag}, b.Ref, nil)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			nested = append(nested, b.Name)
+		default:
+			if b.Repeated && b.Bind != metadata.BindQuery && b.Bind != metadata.BindHeader {
+				return nil, nil, nil, errors.New("repeated field '" + b.Name + "' can only be bound to query or header")
+			}
+			all = append(all, apimeta.FieldBinding{
+				Name:     b.Name,
+				Kind:     b.Kind,
+				Path:     []uint32{b.Tag},
+				Repeated: b.Repeated,
+				Bind:     b.Bind,
+			})
+			call = append(call, b.FieldBinding)
+		}
+	}
+	return all, call, nested, nil
+}
+
+// NestedBindingDiagnostic 返回消息类型字段的 query 绑定不能用 metadata.Call 表示的警告
+func NestedBindingDiagnostic(method string, source apimeta.SourceLocation, nested []string) *apimeta.Diagnostic {
+	if len(nested) == 0 {
+		return nil
+	}
+	return &apimeta.Diagnostic{
+		Severity: apimeta.SeverityWarning,
+		Method:   method,
+		Source:   source,
+		Message:  "query bindings of message fields '" + strings.Join(nested, "', '") + "' are only available in Route.Bindings, metadata.Call drops them",
+	}
+}
+
+// AnnotationDiagnostic 返回注解错误的警告。只返回 metadata.Route 的旧接口不使用注解中的文档和选项，注解写错时不应该丢弃路由
+func AnnotationDiagnostic(method string, source apimeta.SourceLocation, err error) *apimeta.Diagnostic {
+	return &apimeta.Diagnostic{
+		Severity: apimeta.SeverityWarning,
+		Method:   method,
+		Source:   source,
+		Message:  err.Error(),
+	}
+}
+
+// expandBinding 把 msg 的字段展开成 query 绑定，stack 用于检查递归引用
+func expandBinding(all []apimeta.FieldBinding, prefix string, path []uint32, msg *jsonpb.Message, stack []*jsonpb.Message) ([]apimeta.FieldBinding, error) {
+	if msg == nil {
+		return nil, errors.New("unresolved message of field '" + prefix + "'")
+	}
+	for _, m := range stack {
+		if m == msg {
+			return nil, errors.New("recursive message '" + msg.Name + "' bound to '" + prefix + "'")
+		}
+	}
+	stack = append(stack, msg)
+	for i := range msg.Fields {
+		f := &msg.Fields[i]
+		name := prefix + "." + f.Name
+		fieldPath := make([]uint32, len(path)+1)
+		copy(fieldPath, path)
+		fieldPath[len(path)] = f.Tag
+		switch {
+		case f.Kind == jsonpb.MapKind || f.Kind == jsonpb.MessageKind && f.Repeated:
+			return nil, errors.New("field '" + name + "' can not be bound to query")
+		case f.Kind == jsonpb.MessageKind:
+			var err error
+			all, err = expandBinding(all, name, fieldPath, f.Ref, stack)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			all = append(all, apimeta.FieldBinding{
+				Name:     name,
+				Kind:     f.Kind,
+				Path:     fieldPath,
+				Repeated: f.Repeated,
+				Bind:     metadata.BindQuery,
+			})
+		}
+	}
+	return all, nil
+}
@@ -0,0 +1,292 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+	"google.golang.org/grpc/codes"
+)
+
+func bindingOf(name string, kind jsonpb.Kind, tag uint32, bind metadata.BindSource) Binding {
+	return Binding{FieldBinding: metadata.FieldBinding{Name: name, Kind: kind, Tag: tag, Bind: bind}}
+}
+
+func TestResolveResponseBindings(t *testing.T) {
+	repeatedHeader := bindingOf("X-Tag", jsonpb.StringKind, 1, metadata.BindHeader)
+	repeatedHeader.Repeated = true
+	tests := []struct {
+		name     string
+		bindings []Binding
+		want     []apimeta.ResponseTarget
+		wantErr  bool
+	}{
+		{name: "empty"},
+		{name: "header", bindings: []Binding{bindingOf("ETag", jsonpb.StringKind, 1, metadata.BindHeader)}, want: []apimeta.ResponseTarget{apimeta.ResponseHeader}},
+		{name: "status", bindings: []Binding{bindingOf(":status", jsonpb.Int32Kind, 1, metadata.BindHeader)}, want: []apimeta.ResponseTarget{apimeta.ResponseStatus}},
+		{name: "string_status", bindings: []Binding{bindingOf(":status", jsonpb.StringKind, 1, metadata.BindHeader)}, wantErr: true},
+		{name: "query", bindings: []Binding{bindingOf("q", jsonpb.StringKind, 1, metadata.BindQuery)}},
+		{name: "mixed", bindings: []Binding{bindingOf("uid", jsonpb.StringKind, 1, metadata.BindContext), bindingOf("ETag", jsonpb.StringKind, 2, metadata.BindHeader)}, want: []apimeta.ResponseTarget{apimeta.ResponseHeader}},
+		{name: "message", bindings: []Binding{bindingOf("X-User", jsonpb.MessageKind, 1, metadata.BindHeader)}, wantErr: true},
+		{name: "repeated", bindings: []Binding{repeatedHeader}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveResponseBindings(tt.bindings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveResponseBindings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ResolveResponseBindings() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Target != tt.want[i] {
+					t.Errorf("ResolveResponseBindings()[%d].Target = %v, want %v", i, got[i].Target, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveRequestBindings(t *testing.T) {
+	page := &jsonpb.Message{
+		Name: "Page",
+		Fields: []jsonpb.Field{
+			{Name: "size", Kind: jsonpb.Int32Kind, Tag: 1},
+			{Name: "ids", Kind: jsonpb.Int64Kind, Tag: 2, Repeated: true},
+		},
+	}
+	filter := &jsonpb.Message{
+		Name: "Filter",
+		Fields: []jsonpb.Field{
+			{Name: "page", Kind: jsonpb.MessageKind, Ref: page, Tag: 3},
+		},
+	}
+	recursive := &jsonpb.Message{Name: "Node"}
+	recursive.Fields = []jsonpb.Field{{Name: "next", Kind: jsonpb.MessageKind, Ref: recursive, Tag: 1}}
+	withMap := &jsonpb.Message{
+		Name:   "WithMap",
+		Fields: []jsonpb.Field{{Name: "m", Kind: jsonpb.MapKind, Tag: 1}},
+	}
+
+	tags := bindingOf("tag", jsonpb.StringKind, 2, metadata.BindQuery)
+	tags.Repeated = true
+	paramsTags := tags
+	paramsTags.Bind = metadata.BindParams
+	filterBinding := bindingOf("filter", jsonpb.MessageKind, 5, metadata.BindQuery)
+	filterBinding.Ref = filter
+	headerFilter := filterBinding
+	headerFilter.Bind = metadata.BindHeader
+	recursiveBinding := bindingOf("node", jsonpb.MessageKind, 1, metadata.BindQuery)
+	recursiveBinding.Ref = recursive
+	mapBinding := bindingOf("w", jsonpb.MessageKind, 1, metadata.BindQuery)
+	mapBinding.Ref = withMap
+
+	tests := []struct {
+		name       string
+		bindings   []Binding
+		want       []apimeta.FieldBinding
+		wantCall   []metadata.FieldBinding
+		wantNested []string
+		wantErr    bool
+	}{
+		{name: "empty"},
+		{
+			name:     "scalar",
+			bindings: []Binding{bindingOf("id", jsonpb.Int64Kind, 1, metadata.BindParams)},
+			want:     []apimeta.FieldBinding{{Name: "id", Kind: jsonpb.Int64Kind, Path: []uint32{1}, Bind: metadata.BindParams}},
+			wantCall: []metadata.FieldBinding{{Name: "id", Kind: jsonpb.Int64Kind, Tag: 1, Bind: metadata.BindParams}},
+		},
+		{
+			name:     "repeated",
+			bindings: []Binding{tags},
+			want:     []apimeta.FieldBinding{{Name: "tag", Kind: jsonpb.StringKind, Path: []uint32{2}, Repeated: true, Bind: metadata.BindQuery}},
+			wantCall: []metadata.FieldBinding{{Name: "tag", Kind: jsonpb.StringKind, Tag: 2, Bind: metadata.BindQuery}},
+		},
+		{
+			name:     "nested",
+			bindings: []Binding{filterBinding},
+			want: []apimeta.FieldBinding{
+				{Name: "filter.page.size", Kind: jsonpb.Int32Kind, Path: []uint32{5, 3, 1}, Bind: metadata.BindQuery},
+				{Name: "filter.page.ids", Kind: jsonpb.Int64Kind, Path: []uint32{5, 3, 2}, Repeated: true, Bind: metadata.BindQuery},
+			},
+			wantNested: []string{"filter"},
+		},
+		{name: "repeated_params", bindings: []Binding{paramsTags}, wantErr: true},
+		{name: "nested_header", bindings: []Binding{headerFilter}, wantErr: true},
+		{name: "recursive", bindings: []Binding{recursiveBinding}, wantErr: true},
+		{name: "nested_map", bindings: []Binding{mapBinding}, wantErr: true},
+		{name: "map", bindings: []Binding{bindingOf("m", jsonpb.MapKind, 1, metadata.BindQuery)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotCall, gotNested, err := ResolveRequestBindings(tt.bindings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRequestBindings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ResolveRequestBindings() = %v, want %v", got, tt.want)
+				}
+			}
+			if len(gotCall) != 0 || len(tt.wantCall) != 0 {
+				if !reflect.DeepEqual(gotCall, tt.wantCall) {
+					t.Errorf("ResolveRequestBindings() call = %v, want %v", gotCall, tt.wantCall)
+				}
+			}
+			if !reflect.DeepEqual(gotNested, tt.wantNested) {
+				t.Errorf("ResolveRequestBindings() nested = %v, want %v", gotNested, tt.wantNested)
+			}
+		})
+	}
+}
+
+func TestParseHandlerBinding(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    apimeta.HandlerBinding
+		wantErr bool
+	}{
+		{line: "application/json application/json jsonapi", want: apimeta.HandlerBinding{Consumes: "application/json", Produces: "application/json", Handler: "jsonapi"}},
+		{line: "Application/X-Protobuf */* pbapi", want: apimeta.HandlerBinding{Consumes: "application/x-protobuf", Produces: "*/*", Handler: "pbapi"}},
+		{line: "application/json jsonapi", wantErr: true},
+		{line: "json json jsonapi", wantErr: true},
+		{line: "*/json application/json jsonapi", wantErr: true},
+		{line: "application/json application/json json.api", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseHandlerBinding(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHandlerBinding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseHandlerBinding() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryPolicy(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    *apimeta.RetryPolicy
+		wantErr bool
+	}{
+		{
+			line: "attempts=3",
+			want: &apimeta.RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, BackoffMultiplier: 2, Codes: []codes.Code{codes.Unavailable}},
+		},
+		{
+			line: "attempts=4 backoff=50ms max-backoff=2s multiplier=1.5 codes=unavailable,RESOURCE_EXHAUSTED",
+			want: &apimeta.RetryPolicy{MaxAttempts: 4, InitialBackoff: 50 * time.Millisecond, MaxBackoff: 2 * time.Second, BackoffMultiplier: 1.5, Codes: []codes.Code{codes.Unavailable, codes.ResourceExhausted}},
+		},
+		{
+			line: "attempts=2 hedging-delay=30ms",
+			want: &apimeta.RetryPolicy{MaxAttempts: 2, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, BackoffMultiplier: 2, HedgingDelay: 30 * time.Millisecond, Codes: []codes.Code{codes.Unavailable}},
+		},
+		{line: "attempts=1", wantErr: true},
+		{line: "codes=UNAVAILABLE", wantErr: true},
+		{line: "attempts=3 codes=OK", wantErr: true},
+		{line: "attempts=3 codes=BROKEN", wantErr: true},
+		{line: "attempts=3 backoff=2s max-backoff=1s", wantErr: true},
+		{line: "attempts=3 jitter=0.2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseRetryPolicy(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRetryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    apimeta.Attributes
+		wantErr bool
+	}{
+		{name: "empty", comment: "@summary x"},
+		{name: "attrs", comment: "@attr cors.origins = https://a.com, https://b.com\n@attr feature.beta\n@attr body.max-bytes=", want: apimeta.Attributes{"cors.origins": "https://a.com, https://b.com", "feature.beta": "true", "body.max-bytes": ""}},
+		{name: "override", comment: "@attr a=1\n@attr a=2", want: apimeta.Attributes{"a": "2"}},
+		{name: "invalid_key", comment: "@attr cors origins=*", wantErr: true},
+		{name: "empty_key", comment: "@attr =1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAttributes(ExtractAnnotations(tt.comment))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAttributes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    []string
+		wantErr bool
+	}{
+		{comment: "@summary x"},
+		{comment: "@host Admin.Example.com, *.partner.example.com", want: []string{"admin.example.com", "*.partner.example.com"}},
+		{comment: "@host a.*.example.com", wantErr: true},
+		{comment: "@host *", wantErr: true},
+		{comment: "@host -a.example.com", wantErr: true},
+		{comment: "@host a..example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, err := ParseHosts(ExtractAnnotations(tt.comment))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTimeout(t *testing.T) {
+	src := apimeta.SourceLocation{File: "a.proto", Line: 3}
+	tests := []struct {
+		name     string
+		policy   *apimeta.TimeoutPolicy
+		timeout  int64
+		want     time.Duration
+		severity apimeta.Severity
+		diag     bool
+	}{
+		{name: "no_policy", timeout: 1500, want: 1500 * time.Millisecond},
+		{name: "negative_no_policy", timeout: -1, diag: true, severity: apimeta.SeverityError},
+		{name: "negative", policy: &apimeta.TimeoutPolicy{}, timeout: -1, diag: true, severity: apimeta.SeverityError},
+		{name: "clamp", policy: &apimeta.TimeoutPolicy{Max: time.Second, Clamp: true}, timeout: 1500, want: time.Second, diag: true, severity: apimeta.SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diag := ResolveTimeout(tt.policy, "s", "/svc/Call", src, tt.timeout)
+			if got != tt.want {
+				t.Errorf("ResolveTimeout() = %v, want %v", got, tt.want)
+			}
+			if (diag != nil) != tt.diag {
+				t.Fatalf("ResolveTimeout() diag = %v, want %v", diag, tt.diag)
+			}
+			if diag != nil && (diag.Severity != tt.severity || diag.Method != "/svc/Call" || diag.Source != src) {
+				t.Errorf("ResolveTimeout() diag = %+v", diag)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type SourceLocations map[string]*descriptorpb.SourceCodeInfo_Location
+
+func NewSourceLocations(info *descriptorpb.SourceCodeInfo) SourceLocations {
+	locs := make(SourceLocations, len(info.GetLocation()))
+	for _, loc := range info.GetLocation() {
+		locs[descriptor.SourcePathKey(loc.Path)] = loc
+	}
+	return locs
+}
+
+// Get 返回 path 对应的位置和前置注释，line 和 column 从 1 开始
+func (sl SourceLocations) Get(path ...int32) (line int, column int, comments string) {
+	loc := sl[descriptor.SourcePathKey(path)]
+	if loc == nil {
+		return 0, 0, ""
+	}
+	if len(loc.Span) >= 2 {
+		line = int(loc.Span[0]) + 1
+		column = int(loc.Span[1]) + 1
+	}
+	return line, column, loc.GetLeadingComments()
+}
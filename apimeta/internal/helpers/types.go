@@ -5,9 +5,41 @@ import (
 	"github.com/vizee/jsonpb"
 )
 
+// Binding 是消息中设置了 (gapi.bind) 的字段，Ref 是消息类型字段引用的消息
+type Binding struct {
+	metadata.FieldBinding
+	Repeated bool
+	Ref      *jsonpb.Message
+}
+
 type Message struct {
 	*jsonpb.Message
-	Bindings   []metadata.FieldBinding
+	Bindings   []Binding
 	MapEntry   bool
 	Incomplete bool
 }
+
+// HasDroppedField 检查 msg 以及它直接或间接引用的消息是否在 dropped 中
+func HasDroppedField(msg *jsonpb.Message, dropped map[*jsonpb.Message]bool) bool {
+	if len(dropped) == 0 {
+		return false
+	}
+	visited := make(map[*jsonpb.Message]bool)
+	var walk func(m *jsonpb.Message) bool
+	walk = func(m *jsonpb.Message) bool {
+		if m == nil || visited[m] {
+			return false
+		}
+		visited[m] = true
+		if dropped[m] {
+			return true
+		}
+		for i := range m.Fields {
+			if walk(m.Fields[i].Ref) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(msg)
+}
@@ -0,0 +1,165 @@
+package jsonschema
+
+import (
+	"math"
+
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+)
+
+const Draft202012 = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema 是 JSON Schema (draft 2020-12) 中用到的子集
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	PropertyNames        *Schema            `json:"propertyNames,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+type builder struct {
+	root   *jsonpb.Message
+	output bool
+	defs   map[string]*Schema
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func scalarSchema(kind jsonpb.Kind) *Schema {
+	switch kind {
+	case jsonpb.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case jsonpb.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case jsonpb.Int32Kind, jsonpb.Sint32Kind, jsonpb.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32", Minimum: bound(math.MinInt32), Maximum: bound(math.MaxInt32)}
+	case jsonpb.Uint32Kind, jsonpb.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "uint32", Minimum: bound(0), Maximum: bound(math.MaxUint32)}
+	case jsonpb.Int64Kind, jsonpb.Sint64Kind, jsonpb.Sfixed64Kind:
+		return &Schema{Type: "integer", Format: "int64"}
+	case jsonpb.Uint64Kind, jsonpb.Fixed64Kind:
+		return &Schema{Type: "integer", Format: "uint64", Minimum: bound(0)}
+	case jsonpb.BoolKind:
+		return &Schema{Type: "boolean"}
+	case jsonpb.StringKind:
+		return &Schema{Type: "string"}
+	case jsonpb.BytesKind:
+		return &Schema{Type: "string", ContentEncoding: "base64"}
+	}
+	return &Schema{}
+}
+
+// mapKeySchema 描述 map key 在 JSON 对象中的字符串形式
+func mapKeySchema(kind jsonpb.Kind) *Schema {
+	switch {
+	case kind == jsonpb.BoolKind:
+		return &Schema{Pattern: "^(true|false)$"}
+	case kind == jsonpb.Uint32Kind || kind == jsonpb.Uint64Kind || kind == jsonpb.Fixed32Kind || kind == jsonpb.Fixed64Kind:
+		return &Schema{Pattern: "^[0-9]+$"}
+	case jsonpb.IsNumericKind(kind):
+		return &Schema{Pattern: "^-?[0-9]+$"}
+	}
+	return nil
+}
+
+func (b *builder) messageRef(msg *jsonpb.Message) *Schema {
+	// 输出时根消息带有 required，递归引用的根消息需要使用 $defs 中没有 required 的版本
+	if msg == b.root && !b.output {
+		return &Schema{Ref: "#"}
+	}
+	if _, ok := b.defs[msg.Name]; !ok {
+		// 先占位防止递归
+		b.defs[msg.Name] = nil
+		b.defs[msg.Name] = b.messageSchema(msg, false)
+	}
+	return &Schema{Ref: "#/$defs/" + msg.Name}
+}
+
+func (b *builder) valueSchema(kind jsonpb.Kind, ref *jsonpb.Message) *Schema {
+	switch kind {
+	case jsonpb.MessageKind:
+		if ref == nil {
+			return &Schema{Type: "object"}
+		}
+		return b.messageRef(ref)
+	case jsonpb.MapKind:
+		schema := &Schema{Type: "object"}
+		if ref != nil {
+			if key := ref.FieldByTag(1); key != nil {
+				schema.PropertyNames = mapKeySchema(key.Kind)
+			}
+			if value := ref.FieldByTag(2); value != nil {
+				schema.AdditionalProperties = b.valueSchema(value.Kind, value.Ref)
+			}
+		}
+		return schema
+	}
+	return scalarSchema(kind)
+}
+
+func (b *builder) messageSchema(msg *jsonpb.Message, required bool) *Schema {
+	schema := &Schema{
+		Title:      msg.Name,
+		Type:       "object",
+		Properties: make(map[string]*Schema, len(msg.Fields)),
+	}
+	for i := range msg.Fields {
+		field := &msg.Fields[i]
+		if field.Omit == jsonpb.OmitAlways {
+			continue
+		}
+		prop := b.valueSchema(field.Kind, field.Ref)
+		if field.Repeated {
+			prop = &Schema{Type: "array", Items: prop}
+		}
+		schema.Properties[field.Name] = prop
+		if required && field.Omit == jsonpb.OmitProtoEmpty {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+	return schema
+}
+
+func build(msg *jsonpb.Message, output bool) *Schema {
+	b := &builder{
+		root:   msg,
+		output: output,
+		defs:   make(map[string]*Schema),
+	}
+	// 输出时只有 OmitEmpty 的字段可能缺失，输入的字段都是可选的。
+	// 没有设置的消息字段会输出成 {}，所以只有根消息能确定哪些字段是必需的
+	schema := b.messageSchema(msg, output)
+	schema.Schema = Draft202012
+	if len(b.defs) > 0 {
+		schema.Defs = b.defs
+	}
+	return schema
+}
+
+// InputSchema 返回 msg 作为请求体时的 JSON Schema，所有字段都是可选的
+func InputSchema(msg *jsonpb.Message) *Schema {
+	return build(msg, false)
+}
+
+// OutputSchema 返回 msg 作为响应体时的 JSON Schema，根消息中没有设置 omit_empty 的字段总会输出，所以是必需的
+func OutputSchema(msg *jsonpb.Message) *Schema {
+	return build(msg, true)
+}
+
+// RouteSchemas 返回路由请求体和响应体的 JSON Schema，通过 Bindings 从参数提取的字段不在请求体中
+func RouteSchemas(route *metadata.Route) (in *Schema, out *Schema) {
+	return InputSchema(route.Call.In), OutputSchema(route.Call.Out)
+}
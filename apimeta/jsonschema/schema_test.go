@@ -0,0 +1,130 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	validator "github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+	"github.com/vizee/jsonpb/proto"
+)
+
+func testMessages() (req *jsonpb.Message, resp *jsonpb.Message) {
+	user := jsonpb.NewMessage("pkg.User", []jsonpb.Field{
+		{Name: "name", Kind: jsonpb.StringKind, Tag: 1},
+		{Name: "age", Kind: jsonpb.Int32Kind, Tag: 2, Omit: jsonpb.OmitEmpty},
+	}, true, true)
+	entry := jsonpb.NewMessage("pkg.Response.UsersEntry", []jsonpb.Field{
+		{Name: "key", Kind: jsonpb.Int64Kind, Tag: 1},
+		{Name: "value", Kind: jsonpb.MessageKind, Ref: user, Tag: 2},
+	}, true, true)
+	node := &jsonpb.Message{Name: "pkg.Node"}
+	node.Fields = []jsonpb.Field{
+		{Name: "children", Kind: jsonpb.MessageKind, Ref: node, Tag: 1, Repeated: true},
+	}
+	node.BakeTagIndex()
+	node.BakeNameIndex()
+	resp = jsonpb.NewMessage("pkg.Response", []jsonpb.Field{
+		{Name: "users", Kind: jsonpb.MapKind, Ref: entry, Tag: 1},
+		{Name: "tags", Kind: jsonpb.StringKind, Tag: 2, Repeated: true, Omit: jsonpb.OmitEmpty},
+		{Name: "tree", Kind: jsonpb.MessageKind, Ref: node, Tag: 3},
+		{Name: "data", Kind: jsonpb.BytesKind, Tag: 4},
+	}, true, true)
+	req = jsonpb.NewMessage("pkg.Request", []jsonpb.Field{
+		{Name: "uid", Kind: jsonpb.Uint64Kind, Tag: 1},
+	}, true, true)
+	return req, resp
+}
+
+func TestRouteSchemas(t *testing.T) {
+	req, resp := testMessages()
+	in, out := RouteSchemas(&metadata.Route{Call: &metadata.Call{In: req, Out: resp}})
+	if in.Schema != Draft202012 || len(in.Required) != 0 || in.Properties["uid"].Format != "uint64" {
+		t.Fatalf("unexpected input schema %+v", in)
+	}
+	if len(out.Required) != 3 || out.Required[0] != "users" || out.Required[1] != "tree" {
+		t.Fatalf("unexpected required %v", out.Required)
+	}
+	users := out.Properties["users"]
+	if users.Type != "object" || users.PropertyNames.Pattern != "^-?[0-9]+$" || users.AdditionalProperties.Ref != "#/$defs/pkg.User" {
+		t.Fatalf("unexpected map schema %+v", users)
+	}
+	if tags := out.Properties["tags"]; tags.Type != "array" || tags.Items.Type != "string" {
+		t.Fatalf("unexpected repeated schema %+v", tags)
+	}
+	if n := out.Defs["pkg.Node"]; n == nil || n.Properties["children"].Items.Ref != "#/$defs/pkg.Node" {
+		t.Fatalf("unexpected recursive schema %+v", n)
+	}
+	// 没有设置的消息字段输出成 {}，引用的消息不能有 required
+	if u := out.Defs["pkg.User"]; u == nil || len(u.Required) != 0 {
+		t.Fatalf("unexpected user schema %+v", u)
+	}
+
+	j, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(j))
+}
+
+func TestOutputSchemaValidation(t *testing.T) {
+	_, resp := testMessages()
+	data, err := json.Marshal(OutputSchema(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := validator.NewCompiler()
+	c.Draft = validator.Draft2020
+	err = c.AddResource("out.json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := c.Compile("out.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := proto.NewEncoder(nil)
+	user.EmitString(1, "vizee")
+	user.EmitVarint(2, 18)
+	named := proto.NewEncoder(nil)
+	named.EmitVarint(1, 1)
+	named.EmitBytes(2, user.Bytes())
+	// value 没有设置的 map 项
+	empty := proto.NewEncoder(nil)
+	empty.EmitVarint(1, 2)
+	leaf := proto.NewEncoder(nil)
+	leaf.EmitBytes(1, nil)
+	full := proto.NewEncoder(nil)
+	full.EmitBytes(1, named.Bytes())
+	full.EmitBytes(1, empty.Bytes())
+	full.EmitString(2, "tag")
+	full.EmitBytes(3, leaf.Bytes())
+	full.EmitBytes(4, []byte{1, 2})
+
+	for name, pb := range map[string][]byte{
+		"empty": nil,
+		"full":  full.Bytes(),
+	} {
+		var j jsonpb.JsonBuilder
+		err := jsonpb.TranscodeToJson(&j, proto.NewDecoder(pb), resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v any
+		err = json.Unmarshal(j.IntoBytes(), &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := schema.Validate(v); err != nil {
+			t.Errorf("%s: %s does not match schema: %v", name, j.String(), err)
+		}
+	}
+
+	// 根消息的 required 仍然有效
+	if err := schema.Validate(map[string]any{}); err == nil {
+		t.Error("empty object should not match output schema")
+	}
+}
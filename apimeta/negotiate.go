@@ -0,0 +1,93 @@
+package apimeta
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseMediaType 去掉参数并转换成小写
+func parseMediaType(s string) string {
+	if semi := strings.IndexByte(s, ';'); semi >= 0 {
+		s = s[:semi]
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// matchMediaType 判断 a 和 b 是否匹配，任意一方都可以包含通配
+func matchMediaType(a string, b string) bool {
+	if a == "*/*" || b == "*/*" {
+		return true
+	}
+	at, as, _ := strings.Cut(a, "/")
+	bt, bs, _ := strings.Cut(b, "/")
+	return at == bt && (as == bs || as == "*" || bs == "*")
+}
+
+type acceptRange struct {
+	media string
+	q     float64
+}
+
+// parseAccept 解析 Accept 头并按 q 值从高到低排序，q=0 的项单独返回，表示客户端明确拒绝的媒体类型
+func parseAccept(accept string) (ranges []acceptRange, rejected []string) {
+	for _, item := range strings.Split(accept, ",") {
+		r := acceptRange{media: parseMediaType(item), q: 1}
+		if r.media == "" {
+			continue
+		}
+		for _, param := range strings.Split(item, ";")[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(k) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.q > 0 {
+			ranges = append(ranges, r)
+		} else {
+			rejected = append(rejected, r.media)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges, rejected
+}
+
+func isRejected(rejected []string, media string) bool {
+	for _, r := range rejected {
+		if r == media {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectHandler 根据请求的 Content-Type 和 Accept 头选择 handler。
+// 路由没有声明 Handlers 时总是返回 Call.Handler；声明了但是没有匹配项时返回 false，网关应该响应 415 或 406。
+// contentType 为空（比如 GET 请求）时不检查 Consumes，accept 为空时等同于 `*/*`。
+func (r *Route) SelectHandler(contentType string, accept string) (string, bool) {
+	if len(r.Handlers) == 0 {
+		return r.Call.Handler, true
+	}
+	ct := parseMediaType(contentType)
+	ranges, rejected := parseAccept(accept)
+	if strings.TrimSpace(accept) == "" {
+		ranges = []acceptRange{{media: "*/*", q: 1}}
+	}
+	for _, ar := range ranges {
+		for i := range r.Handlers {
+			h := &r.Handlers[i]
+			if isRejected(rejected, h.Produces) {
+				continue
+			}
+			if (ct == "" || matchMediaType(h.Consumes, ct)) && matchMediaType(h.Produces, ar.media) {
+				return h.Handler, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,47 @@
+package apimeta
+
+import (
+	"testing"
+
+	"github.com/vizee/gapi/metadata"
+)
+
+func TestRouteSelectHandler(t *testing.T) {
+	route := &Route{
+		Route: &metadata.Route{Call: &metadata.Call{Handler: "jsonapi"}},
+		Handlers: []HandlerBinding{
+			{Consumes: "application/json", Produces: "application/json", Handler: "jsonapi"},
+			{Consumes: "application/x-www-form-urlencoded", Produces: "application/json", Handler: "formapi"},
+			{Consumes: "application/x-protobuf", Produces: "application/x-protobuf", Handler: "pbapi"},
+		},
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		want        string
+		wantOk      bool
+	}{
+		{name: "json", contentType: "application/json; charset=utf-8", accept: "application/json", want: "jsonapi", wantOk: true},
+		{name: "form", contentType: "application/x-www-form-urlencoded", want: "formapi", wantOk: true},
+		{name: "protobuf", contentType: "Application/X-Protobuf", accept: "application/*", want: "pbapi", wantOk: true},
+		{name: "no_body", accept: "application/x-protobuf", want: "pbapi", wantOk: true},
+		{name: "quality", accept: "application/json;q=0.5, application/x-protobuf", want: "pbapi", wantOk: true},
+		{name: "q0", contentType: "application/json", accept: "application/json;q=0, */*;q=0.1", wantOk: false},
+		{name: "unsupported_media_type", contentType: "text/plain", wantOk: false},
+		{name: "not_acceptable", contentType: "application/json", accept: "text/html", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := route.SelectHandler(tt.contentType, tt.accept)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("SelectHandler() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+
+	plain := &Route{Route: &metadata.Route{Call: &metadata.Call{Handler: "jsonapi"}}}
+	if got, ok := plain.SelectHandler("text/plain", "text/html"); got != "jsonapi" || !ok {
+		t.Errorf("SelectHandler() = %q, %v", got, ok)
+	}
+}
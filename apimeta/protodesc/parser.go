@@ -2,11 +2,12 @@ package protodesc
 
 import (
 	"errors"
-	"strings"
 	"time"
 
+	"github.com/vizee/gapi-plus/apimeta"
 	"github.com/vizee/gapi-plus/apimeta/internal/helpers"
 	"github.com/vizee/gapi-plus/apimeta/internal/slices"
+	"github.com/vizee/gapi-plus/proto/descriptor"
 	annotation "github.com/vizee/gapi-proto-go/gapi"
 	"github.com/vizee/gapi/metadata"
 	"github.com/vizee/jsonpb"
@@ -14,10 +15,53 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+// messageSlabSize 是批量分配消息的数量，大型 descriptor set 中消息数量通常以万计
+const messageSlabSize = 256
+
+type messageSlot struct {
+	msg  helpers.Message
+	desc jsonpb.Message
+}
+
+// fieldFixup 记录引用了未完成消息的字段，在 resolveFixups 时回填 Ref 和 map 信息。
+// ref 为 nil 时表示相对名称 name 还没有找到定义，需要从 scope 开始重新查找；
+// binding 为 true 时 index 指向 msg.Bindings，否则指向 msg.Fields
+type fieldFixup struct {
+	msg     *helpers.Message
+	index   int
+	binding bool
+	ref     *helpers.Message
+	scope   string
+	name    string
+}
+
+type fieldInfo struct {
+	fd   *descriptorpb.FieldDescriptorProto
+	name string
+	kind jsonpb.Kind
+	bind annotation.FIELD_BIND
+	omit bool
+}
+
 type Parser struct {
-	ns     []string
+	// TimeoutPolicy 不为 nil 时用于校验方法的超时时间
+	TimeoutPolicy *apimeta.TimeoutPolicy
+	// StrictFields 为 true 时，引用了被丢弃字段的方法会被视为错误
+	StrictFields bool
+
+	// prefix 是当前作用域的全名（以 '.' 开头），scopes 记录每次 enter 前 prefix 的长度，leave 时直接截断
 	prefix string
+	scopes []int
 	msgs   map[string]*helpers.Message
+	slab   []messageSlot
+	fixups []fieldFixup
+	fields []fieldInfo
+	file   string
+	locs   helpers.SourceLocations
+	diags  []apimeta.Diagnostic
+
+	dropped     []apimeta.DroppedField
+	droppedMsgs map[*jsonpb.Message]bool
 }
 
 func NewParser() *Parser {
@@ -27,29 +71,104 @@ func NewParser() *Parser {
 }
 
 func (p *Parser) enter(ns string) {
-	p.ns = append(p.ns, ns)
-	p.prefix = "." + strings.Join(p.ns, ".")
+	p.scopes = append(p.scopes, len(p.prefix))
+	if ns != "" {
+		p.prefix = p.prefix + "." + ns
+	}
 }
 
 func (p *Parser) leave() {
-	p.ns = p.ns[:len(p.ns)-1]
-	p.prefix = "." + strings.Join(p.ns, ".")
+	n := len(p.scopes) - 1
+	p.prefix = p.prefix[:p.scopes[n]]
+	p.scopes = p.scopes[:n]
 }
 
 func (p *Parser) getMessage(fullName string) *helpers.Message {
 	msg := p.msgs[fullName]
 	if msg == nil {
-		msg = &helpers.Message{
-			Message: &jsonpb.Message{
-				Name: normalName(fullName),
-			},
-			Incomplete: true,
+		if len(p.slab) == 0 {
+			p.slab = make([]messageSlot, messageSlabSize)
 		}
+		slot := &p.slab[0]
+		p.slab = p.slab[1:]
+
+		// fullName 作为 map 的 key 和消息名共用同一份内存
+		slot.desc.Name = normalName(fullName)
+		slot.msg.Message = &slot.desc
+		slot.msg.Incomplete = true
+		msg = &slot.msg
 		p.msgs[fullName] = msg
 	}
 	return msg
 }
 
+// lookupScoped 按照 protobuf 的规则从 scope 开始逐级向外查找相对名称 name
+func (p *Parser) lookupScoped(scope string, name string) *helpers.Message {
+	for {
+		msg := p.msgs[scope+"."+name]
+		if msg != nil {
+			return msg
+		}
+		if scope == "" {
+			return nil
+		}
+		dot := len(scope) - 1
+		for dot >= 0 && scope[dot] != '.' {
+			dot--
+		}
+		if dot < 0 {
+			return nil
+		}
+		scope = scope[:dot]
+	}
+}
+
+func (p *Parser) resolveFixups() {
+	pending := p.fixups[:0]
+	for _, fix := range p.fixups {
+		ref := fix.ref
+		if ref == nil {
+			ref = p.lookupScoped(fix.scope, fix.name)
+		}
+		if ref == nil || ref.Incomplete {
+			pending = append(pending, fix)
+			continue
+		}
+		if fix.binding {
+			b := &fix.msg.Bindings[fix.index]
+			b.Ref = ref.Message
+			if b.Repeated && ref.MapEntry {
+				b.Kind = jsonpb.MapKind
+				b.Repeated = false
+			}
+			continue
+		}
+		field := &fix.msg.Fields[fix.index]
+		field.Ref = ref.Message
+		if field.Repeated && ref.MapEntry {
+			field.Kind = jsonpb.MapKind
+			field.Repeated = false
+		}
+	}
+	for i := len(pending); i < len(p.fixups); i++ {
+		p.fixups[i] = fieldFixup{}
+	}
+	p.fixups = pending
+}
+
+func (p *Parser) dropField(msg *helpers.Message, fd *descriptorpb.FieldDescriptorProto, reason string) {
+	p.dropped = append(p.dropped, apimeta.DroppedField{
+		Message: msg.Name,
+		Field:   fd.GetName(),
+		Type:    fd.GetType(),
+		Reason:  reason,
+	})
+	if p.droppedMsgs == nil {
+		p.droppedMsgs = make(map[*jsonpb.Message]bool)
+	}
+	p.droppedMsgs[msg.Message] = true
+}
+
 func (p *Parser) parseMessage(md *descriptorpb.DescriptorProto) error {
 	p.enter(md.GetName())
 	defer p.leave()
@@ -67,59 +186,99 @@ func (p *Parser) parseMessage(md *descriptorpb.DescriptorProto) error {
 		}
 	}
 
-	fields := make([]jsonpb.Field, 0, len(md.Field))
-	var bindings []metadata.FieldBinding
+	// 先收集字段信息再按实际数量分配 fields 和 bindings，p.fields 在消息之间复用
+	infos := p.fields[:0]
+	numBindings := 0
 	for _, fd := range md.Field {
-		ty := fd.GetType()
-		kind, ok := helpers.GetTypeKind(ty)
+		kind, ok := helpers.GetTypeKind(fd.GetType())
 		if !ok {
+			p.dropField(msg, fd, "unsupported field type")
 			continue
 		}
-
-		name := fd.GetName()
-		alias := getOption(proto.GetExtension(fd.Options, annotation.E_Alias), "")
-		if alias != "" {
-			name = alias
+		info := fieldInfo{
+			fd:   fd,
+			name: fd.GetName(),
+			kind: kind,
 		}
+		// 大部分字段没有 options，跳过 GetExtension 可以省掉大量开销
+		if fd.Options != nil {
+			alias := getOption(proto.GetExtension(fd.Options, annotation.E_Alias), "")
+			if alias != "" {
+				info.name = alias
+			}
+			info.bind = getOption(proto.GetExtension(fd.Options, annotation.E_Bind), annotation.FIELD_BIND_FROM_DEFAULT)
+			info.omit = getOption(proto.GetExtension(fd.Options, annotation.E_OmitEmpty), false)
+		}
+		if info.bind != annotation.FIELD_BIND_FROM_DEFAULT {
+			numBindings++
+		}
+		infos = append(infos, info)
+	}
+	p.fields = infos
 
-		bind := getOption(proto.GetExtension(fd.Options, annotation.E_Bind), annotation.FIELD_BIND_FROM_DEFAULT)
-
-		if bind == annotation.FIELD_BIND_FROM_DEFAULT {
-			repeated := fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
-
-			var msgRef *jsonpb.Message
-			if kind == jsonpb.MessageKind {
-				refName := fd.GetTypeName()
-				if !strings.HasPrefix(refName, ".") {
-					// 这里只搜索了嵌套 scope，按照标准实现应该向上搜索到根
-					refName = p.prefix + "." + refName
-				}
+	fields := make([]jsonpb.Field, 0, len(infos)-numBindings)
+	var bindings []helpers.Binding
+	if numBindings > 0 {
+		bindings = make([]helpers.Binding, 0, numBindings)
+	}
+	for i := range infos {
+		info := &infos[i]
+		fd := info.fd
 
-				ref := p.getMessage(refName)
-				msgRef = ref.Message
-				// map entry 一般从 nested 提供，不需要推迟处理
+		kind := info.kind
+		repeated := fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		var (
+			ref *helpers.Message
+			fix *fieldFixup
+		)
+		if kind == jsonpb.MessageKind {
+			refName := fd.GetTypeName()
+			if len(refName) > 0 && refName[0] == '.' {
+				ref = p.getMessage(refName)
+			} else {
+				ref = p.lookupScoped(p.prefix, refName)
+			}
+			if ref != nil && !ref.Incomplete {
 				if repeated && ref.MapEntry {
+					kind = jsonpb.MapKind
 					repeated = false
 				}
+			} else {
+				fix = &fieldFixup{
+					msg: msg,
+					ref: ref,
+				}
+				if ref == nil {
+					fix.scope = p.prefix
+					fix.name = refName
+				}
 			}
+		}
+		var refMsg *jsonpb.Message
+		if ref != nil {
+			refMsg = ref.Message
+		}
 
-			omit := jsonpb.OmitProtoEmpty
-			omitEmpty := getOption(proto.GetExtension(fd.Options, annotation.E_OmitEmpty), false)
-			if omitEmpty {
-				omit = jsonpb.OmitEmpty
-			}
-
-			fields = append(fields, jsonpb.Field{
-				Name:     name,
+		if info.bind == annotation.FIELD_BIND_FROM_DEFAULT {
+			field := jsonpb.Field{
+				Name:     info.name,
 				Kind:     kind,
-				Ref:      msgRef,
+				Ref:      refMsg,
 				Tag:      uint32(fd.GetNumber()),
 				Repeated: repeated,
-				Omit:     omit,
-			})
+				Omit:     jsonpb.OmitProtoEmpty,
+			}
+			if info.omit {
+				field.Omit = jsonpb.OmitEmpty
+			}
+			if fix != nil {
+				fix.index = len(fields)
+				p.fixups = append(p.fixups, *fix)
+			}
+			fields = append(fields, field)
 		} else {
 			var bindSource metadata.BindSource
-			switch bind {
+			switch info.bind {
 			case annotation.FIELD_BIND_FROM_QUERY:
 				bindSource = metadata.BindQuery
 			case annotation.FIELD_BIND_FROM_PARAMS:
@@ -129,40 +288,53 @@ func (p *Parser) parseMessage(md *descriptorpb.DescriptorProto) error {
 			case annotation.FIELD_BIND_FROM_CONTEXT:
 				bindSource = metadata.BindContext
 			}
-			bindings = append(bindings, metadata.FieldBinding{
-				Name: name,
-				Kind: kind,
-				Tag:  uint32(fd.GetNumber()),
-				Bind: bindSource,
+			if fix != nil {
+				fix.index = len(bindings)
+				fix.binding = true
+				p.fixups = append(p.fixups, *fix)
+			}
+			bindings = append(bindings, helpers.Binding{
+				FieldBinding: metadata.FieldBinding{
+					Name: info.name,
+					Kind: kind,
+					Tag:  uint32(fd.GetNumber()),
+					Bind: bindSource,
+				},
+				Repeated: repeated,
+				Ref:      refMsg,
 			})
 		}
 	}
+	for i := range infos {
+		infos[i] = fieldInfo{}
+	}
 
-	msg.Fields = slices.Shrink(fields)
+	msg.Fields = fields
 	msg.BakeNameIndex()
 	msg.BakeTagIndex()
 
-	msg.Bindings = slices.Shrink(bindings)
+	msg.Bindings = bindings
 	msg.MapEntry = md.Options != nil && md.Options.GetMapEntry()
 	msg.Incomplete = false
 
 	return nil
 }
 
-func (p *Parser) parseService(routes []*metadata.Route, sd *descriptorpb.ServiceDescriptorProto, ignoreError bool) ([]*metadata.Route, error) {
+// parseService 解析服务中的路由，legacy 为 true 时注解的错误只记录为警告
+func (p *Parser) parseService(routes []*apimeta.Route, sd *descriptorpb.ServiceDescriptorProto, index int32, ignoreError bool, legacy bool) ([]*apimeta.Route, error) {
 	server := getOption(proto.GetExtension(sd.Options, annotation.E_Server), "")
 	if server == "" {
 		if ignoreError {
-			return nil, nil
+			return routes, nil
 		}
 		return nil, errors.New("invalid service name '" + sd.GetName() + "'")
 	}
 
 	commonUses, _ := proto.GetExtension(sd.Options, annotation.E_Use).([]string)
 	for _, use := range commonUses {
-		if !helpers.CheckMiddlewareName(use) {
+		if !apimeta.CheckMiddlewareName(use) {
 			if ignoreError {
-				return nil, nil
+				return routes, nil
 			}
 			return nil, errors.New("invalid middleware name '" + use + "'")
 		}
@@ -172,15 +344,45 @@ func (p *Parser) parseService(routes []*metadata.Route, sd *descriptorpb.Service
 	defaultTimeout := getOption(proto.GetExtension(sd.Options, annotation.E_DefaultTimeout), int64(0))
 	pathPrefix := getOption(proto.GetExtension(sd.Options, annotation.E_PathPrefix), "")
 
+	serviceFullname := normalName(p.prefix + "." + sd.GetName())
+	line, column, serviceComments := p.locs.Get(descriptor.ServicePath(index)...)
+	serviceAns := helpers.ExtractAnnotations(serviceComments)
+	var serviceAttrs apimeta.Attributes
+	serviceHosts, err := helpers.ParseHosts(serviceAns)
+	if err == nil {
+		serviceAttrs, err = helpers.ParseAttributes(serviceAns)
+	}
+	if err != nil {
+		switch {
+		case legacy:
+			p.diags = append(p.diags, *helpers.AnnotationDiagnostic(serviceFullname, apimeta.SourceLocation{File: p.file, Line: line, Column: column}, err))
+		case ignoreError:
+			return routes, nil
+		default:
+			return nil, err
+		}
+	}
+	service := &apimeta.Service{
+		Name:           serviceFullname,
+		File:           p.file,
+		Server:         server,
+		PathPrefix:     pathPrefix,
+		DefaultHandler: defaultHandler,
+		DefaultTimeout: time.Duration(defaultTimeout) * time.Millisecond,
+		Use:            commonUses,
+		Hosts:          serviceHosts,
+		Attributes:     serviceAttrs,
+	}
+
 walkmd:
-	for _, md := range sd.Method {
+	for i, md := range sd.Method {
 		httpOpt, ok := proto.GetExtension(md.Options, annotation.E_Http).(*annotation.Http)
 		if !ok || httpOpt == nil {
 			continue
 		}
 
 		for _, use := range httpOpt.Use {
-			if !helpers.CheckMiddlewareName(use) {
+			if !apimeta.CheckMiddlewareName(use) {
 				if ignoreError {
 					continue walkmd
 				}
@@ -192,32 +394,41 @@ walkmd:
 		if handler == "" {
 			handler = defaultHandler
 		}
-		var (
-			method string
-			path   string
-		)
-		switch t := httpOpt.Pattern.(type) {
-		case *annotation.Http_Get:
-			method = "GET"
-			path = t.Get
-		case *annotation.Http_Post:
-			method = "POST"
-			path = t.Post
-		case *annotation.Http_Put:
-			method = "PUT"
-			path = t.Put
-		case *annotation.Http_Delete:
-			method = "DELETE"
-			path = t.Delete
-		case *annotation.Http_Patch:
-			method = "PATCH"
-			path = t.Patch
-		case *annotation.Http_Custom:
-			method = t.Custom.Method
-			path = t.Custom.Path
-		}
-
-		if handler == "" || method == "" || path == "" || md.GetClientStreaming() || md.GetServerStreaming() {
+		method, path := descriptor.HttpRule(httpOpt)
+
+		if method == "" || path == "" || md.GetClientStreaming() || md.GetServerStreaming() {
+			if ignoreError {
+				continue
+			}
+			return nil, errors.New("invalid method '" + md.GetName() + "'")
+		}
+
+		fullMethod := helpers.ConcatFullMethodName(serviceFullname, md.GetName())
+		line, column, comments := p.locs.Get(descriptor.MethodPath(index, int32(i))...)
+		route := &apimeta.Route{
+			Source: apimeta.SourceLocation{
+				File:   p.file,
+				Line:   line,
+				Column: column,
+			},
+			Service: service,
+		}
+		err := helpers.FillRouteAnnotations(route, serviceAns, helpers.ExtractAnnotations(comments))
+		if err != nil {
+			switch {
+			case legacy:
+				p.diags = append(p.diags, *helpers.AnnotationDiagnostic(fullMethod, route.Source, err))
+			case ignoreError:
+				continue
+			default:
+				return nil, err
+			}
+		}
+		// 没有指定 handler 时使用第一个按媒体类型协商的 handler
+		if handler == "" && len(route.Handlers) > 0 {
+			handler = route.Handlers[0].Handler
+		}
+		if handler == "" {
 			if ignoreError {
 				continue
 			}
@@ -228,44 +439,108 @@ walkmd:
 		if timeout == 0 {
 			timeout = defaultTimeout
 		}
-
-		sd.GetName()
-		serviceFullname := normalName(p.prefix + "." + sd.GetName())
+		timeoutDuration, diag := helpers.ResolveTimeout(p.TimeoutPolicy, server, fullMethod, route.Source, timeout)
+		if diag != nil {
+			p.diags = append(p.diags, *diag)
+			if diag.Severity == apimeta.SeverityError {
+				if ignoreError {
+					continue
+				}
+				return nil, diag
+			}
+		}
 
 		inMsg := p.getMessage(md.GetInputType())
-		routes = append(routes, &metadata.Route{
+		outMsg := p.getMessage(md.GetOutputType())
+		if p.StrictFields && (helpers.HasDroppedField(inMsg.Message, p.droppedMsgs) || helpers.HasDroppedField(outMsg.Message, p.droppedMsgs)) {
+			diag := &apimeta.Diagnostic{
+				Severity: apimeta.SeverityError,
+				Method:   fullMethod,
+				Source:   route.Source,
+				Message:  "method '" + md.GetName() + "' references messages with dropped fields",
+			}
+			p.diags = append(p.diags, *diag)
+			if ignoreError {
+				continue
+			}
+			return nil, diag
+		}
+
+		bindings, callBindings, nested, err := helpers.ResolveRequestBindings(inMsg.Bindings)
+		if err != nil {
+			if ignoreError {
+				continue
+			}
+			return nil, errors.New("invalid method '" + md.GetName() + "': " + err.Error())
+		}
+		route.Bindings = bindings
+		if diag := helpers.NestedBindingDiagnostic(fullMethod, route.Source, nested); diag != nil {
+			p.diags = append(p.diags, *diag)
+		}
+		respBindings, err := helpers.ResolveResponseBindings(outMsg.Bindings)
+		if err != nil {
+			if ignoreError {
+				continue
+			}
+			return nil, errors.New("invalid method '" + md.GetName() + "': " + err.Error())
+		}
+		route.ResponseBindings = respBindings
+
+		route.Route = &metadata.Route{
 			Method: method,
 			Path:   pathPrefix + path,
 			Use:    slices.Merge(commonUses, httpOpt.Use),
 			Call: &metadata.Call{
 				Server:   server,
 				Handler:  handler,
-				Method:   helpers.ConcatFullMethodName(serviceFullname, md.GetName()),
+				Method:   fullMethod,
 				In:       inMsg.Message,
-				Out:      p.getMessage(md.GetOutputType()).Message,
-				Bindings: inMsg.Bindings,
-				Timeout:  time.Duration(timeout) * time.Millisecond,
+				Out:      outMsg.Message,
+				Bindings: callBindings,
+				Timeout:  timeoutDuration,
 			},
-		})
+		}
+		routes = append(routes, route)
 	}
 
 	return routes, nil
 }
 
+// AddFile 解析文件中的消息和路由。注释中注解的错误不会丢弃路由，只作为警告记录在 Diagnostics 中
 func (p *Parser) AddFile(routes []*metadata.Route, fd *descriptorpb.FileDescriptorProto, ignoreError bool) ([]*metadata.Route, error) {
+	apiRoutes, err := p.addFile(nil, fd, ignoreError, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range apiRoutes {
+		routes = append(routes, r.Route)
+	}
+	return routes, nil
+}
+
+// AddFileAPIRoutes 与 AddFile 相同，但是返回的路由附带了注释中的文档信息和源码位置，注解的错误与其他错误一样处理
+func (p *Parser) AddFileAPIRoutes(routes []*apimeta.Route, fd *descriptorpb.FileDescriptorProto, ignoreError bool) ([]*apimeta.Route, error) {
+	return p.addFile(routes, fd, ignoreError, false)
+}
+
+func (p *Parser) addFile(routes []*apimeta.Route, fd *descriptorpb.FileDescriptorProto, ignoreError bool, legacy bool) ([]*apimeta.Route, error) {
 	p.enter(fd.GetPackage())
 	defer p.leave()
 
+	p.file = fd.GetName()
+	p.locs = helpers.NewSourceLocations(fd.SourceCodeInfo)
+
 	for _, dp := range fd.MessageType {
 		err := p.parseMessage(dp)
 		if err != nil {
 			return nil, err
 		}
 	}
+	p.resolveFixups()
 
-	for _, sd := range fd.Service {
+	for i, sd := range fd.Service {
 		var err error
-		routes, err = p.parseService(routes, sd, ignoreError)
+		routes, err = p.parseService(routes, sd, int32(i), ignoreError, legacy)
 		if err != nil {
 			return nil, err
 		}
@@ -274,8 +549,25 @@ func (p *Parser) AddFile(routes []*metadata.Route, fd *descriptorpb.FileDescript
 	return routes, nil
 }
 
+// Diagnostics 返回解析路由过程中产生的诊断信息
+func (p *Parser) Diagnostics() []apimeta.Diagnostic {
+	return p.diags
+}
+
+// DroppedFields 返回所有已解析消息中因为类型不支持而被丢弃的字段
+func (p *Parser) DroppedFields() []apimeta.DroppedField {
+	return p.dropped
+}
+
 func (p *Parser) CheckIncomplete() []string {
+	p.resolveFixups()
+
 	var incomplete []string
+	for _, fix := range p.fixups {
+		if fix.ref == nil {
+			incomplete = append(incomplete, normalName(fix.scope+"."+fix.name))
+		}
+	}
 	for _, m := range p.msgs {
 		if m.Incomplete {
 			incomplete = append(incomplete, m.Name)
@@ -0,0 +1,149 @@
+package protodesc
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// makeLargeFileSet 生成 files 个文件，每个文件有 msgs 个消息和一个包含 msgs/2 个方法的服务。
+// 字段会引用同文件、前一个文件中的消息，以及嵌套消息和 map。
+func makeLargeFileSet(files int, msgs int) []*descriptorpb.FileDescriptorProto {
+	scalar := func(name string, num int32, ty descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(num),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   ty.Enum(),
+		}
+	}
+	ref := func(name string, num int32, typeName string, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typeName),
+		}
+	}
+
+	fds := make([]*descriptorpb.FileDescriptorProto, 0, files)
+	for i := 0; i < files; i++ {
+		pkg := "bench.pkg" + strconv.Itoa(i)
+		fd := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("bench/file" + strconv.Itoa(i) + ".proto"),
+			Package: proto.String(pkg),
+		}
+		if i > 0 {
+			fd.Dependency = []string{"bench/file" + strconv.Itoa(i-1) + ".proto"}
+		}
+		for j := 0; j < msgs; j++ {
+			msgName := "Message" + strconv.Itoa(j)
+			fullName := "." + pkg + "." + msgName
+			aliased := scalar("user_id", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+			aliased.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(aliased.Options, annotation.E_Alias, "uid")
+			proto.SetExtension(aliased.Options, annotation.E_Bind, annotation.FIELD_BIND_FROM_QUERY)
+
+			md := &descriptorpb.DescriptorProto{
+				Name: proto.String(msgName),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					scalar("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					scalar("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					aliased,
+					scalar("score", 4, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+					scalar("flag", 5, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+					ref("nested", 6, fullName+".Nested", descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+					ref("attrs", 7, fullName+".AttrsEntry", descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+					ref("next", 8, "."+pkg+".Message"+strconv.Itoa((j+1)%msgs), descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Nested"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							scalar("value", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+						},
+					},
+					{
+						Name: proto.String("AttrsEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							scalar("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+							scalar("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			}
+			if i > 0 {
+				md.Field = append(md.Field, ref("prev", 9, ".bench.pkg"+strconv.Itoa(i-1)+"."+msgName, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL))
+			}
+			fd.MessageType = append(fd.MessageType, md)
+		}
+
+		sd := &descriptorpb.ServiceDescriptorProto{
+			Name:    proto.String("BenchService"),
+			Options: &descriptorpb.ServiceOptions{},
+		}
+		proto.SetExtension(sd.Options, annotation.E_Server, "bench-server")
+		proto.SetExtension(sd.Options, annotation.E_DefaultHandler, "jsonapi")
+		proto.SetExtension(sd.Options, annotation.E_PathPrefix, "/file"+strconv.Itoa(i))
+		for j := 0; j+1 < msgs; j += 2 {
+			mo := &descriptorpb.MethodOptions{}
+			proto.SetExtension(mo, annotation.E_Http, &annotation.Http{
+				Pattern: &annotation.Http_Post{Post: "/method" + strconv.Itoa(j)},
+			})
+			sd.Method = append(sd.Method, &descriptorpb.MethodDescriptorProto{
+				Name:       proto.String("Method" + strconv.Itoa(j)),
+				InputType:  proto.String("." + pkg + ".Message" + strconv.Itoa(j)),
+				OutputType: proto.String("." + pkg + ".Message" + strconv.Itoa(j+1)),
+				Options:    mo,
+			})
+		}
+		fd.Service = append(fd.Service, sd)
+
+		// 与 descriptor set 一样经过一次序列化，使 options 中的扩展以未解析的形式出现
+		data, err := proto.Marshal(fd)
+		if err != nil {
+			panic(err)
+		}
+		fd = &descriptorpb.FileDescriptorProto{}
+		err = proto.Unmarshal(data, fd)
+		if err != nil {
+			panic(err)
+		}
+		fds = append(fds, fd)
+	}
+	return fds
+}
+
+func benchmarkParser(b *testing.B, files int, msgs int) {
+	fds := makeLargeFileSet(files, msgs)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var routes []*apimeta.Route
+		p := NewParser()
+		for _, fd := range fds {
+			var err error
+			routes, err = p.AddFileAPIRoutes(routes, fd, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		if incomplete := p.CheckIncomplete(); len(incomplete) > 0 {
+			b.Fatal("incomplete", incomplete)
+		}
+	}
+}
+
+func BenchmarkParser_100x50(b *testing.B) {
+	benchmarkParser(b, 100, 50)
+}
+
+func BenchmarkParser_1000x50(b *testing.B) {
+	benchmarkParser(b, 1000, 50)
+}
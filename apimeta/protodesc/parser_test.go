@@ -3,9 +3,14 @@ package protodesc
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/vizee/gapi-plus/apimeta"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
 	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -40,3 +45,282 @@ func TestParseRoutes(t *testing.T) {
 	}
 	t.Log(string(j))
 }
+
+func TestParseAPIRoutes(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/pdtest/pdtest.pd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var routes []*apimeta.Route
+	p := NewParser()
+	for _, fd := range fds.File {
+		routes, err = p.AddFileAPIRoutes(routes, fd, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range routes {
+		if r.Source.File != "pdtest.proto" || r.Source.Line == 0 {
+			t.Fatalf("route %s source = %s", r.Path, r.Source)
+		}
+		switch r.Path {
+		case "/path/prefix/add":
+			if r.Summary != "Add" || r.Description != "加法" || !r.Deprecated || len(r.ResponseBindings) != 2 || r.ResponseBindings[1].Target != apimeta.ResponseStatus || len(r.Handlers) != 2 || r.Handlers[1].Handler != "pbapi" || r.Retry == nil || r.Retry.MaxAttempts != 3 || !r.MatchHost("a.partner.example.com") {
+				t.Fatalf("route %s: %+v", r.Path, r)
+			}
+		case "/path/prefix/say":
+			if r.Summary != "Say" || r.Deprecated || r.FieldMask != "fields" || r.Cache == nil || r.Cache.CacheControl() != "private, max-age=30" || r.Retry != nil ||
+				len(r.Attributes) != 3 || r.Attributes["body.max-bytes"] != "4096" || r.Attributes["cors.origins"] != "*" || r.Attributes["feature.beta"] != "true" || len(r.Hosts) != 1 || r.MatchHost("api.example.com") {
+				t.Fatalf("route %s: %+v", r.Path, r)
+			}
+			if len(r.Bindings) != 4 || !r.Bindings[1].Repeated || r.Bindings[3].Name != "page.offset" || len(r.Bindings[3].Path) != 2 || r.Bindings[3].Path[1] != 2 || len(r.Call.Bindings) != 2 || r.Call.Bindings[1].Name != "tag" {
+				t.Fatalf("route %s bindings: %+v", r.Path, r.Bindings)
+			}
+		}
+	}
+	if diags := p.Diagnostics(); len(diags) != 1 || diags[0].Severity != apimeta.SeverityWarning || !strings.Contains(diags[0].Message, "'page'") {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+}
+
+func TestParseRoutesWithTimeoutPolicy(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/pdtest/pdtest.pd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewParser()
+	p.TimeoutPolicy = &apimeta.TimeoutPolicy{Max: 6 * time.Second}
+	var routes []*metadata.Route
+	for _, fd := range fds.File {
+		routes, err = p.AddFile(routes, fd, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(routes) != 1 || len(p.Diagnostics()) != 1 {
+		t.Fatal("unexpected result", routes, p.Diagnostics())
+	}
+	t.Log(p.Diagnostics()[0].Error())
+
+	p = NewParser()
+	p.TimeoutPolicy = &apimeta.TimeoutPolicy{Max: 6 * time.Second, Clamp: true}
+	routes = nil
+	for _, fd := range fds.File {
+		routes, err = p.AddFile(routes, fd, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range routes {
+		if r.Call.Timeout > 6*time.Second {
+			t.Fatalf("route %s timeout = %v", r.Path, r.Call.Timeout)
+		}
+	}
+}
+
+func TestParseRelativeTypeNames(t *testing.T) {
+	msgField := func(name string, num int32, typeName string, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typeName),
+		}
+	}
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("relative.proto"),
+		Package: proto.String("a.b"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Outer"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					msgField("later", 1, "Later", descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+					msgField("other", 2, "c.Other", descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+					msgField("entries", 3, "EntriesEntry", descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("EntriesEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("key"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+							{Name: proto.String("value"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+			{Name: proto.String("Later")},
+		},
+	}
+	other := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("other.proto"),
+		Package:     proto.String("a.c"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Other")}},
+	}
+
+	p := NewParser()
+	for _, f := range []*descriptorpb.FileDescriptorProto{fd, other} {
+		_, err := p.AddFile(nil, f, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if incomplete := p.CheckIncomplete(); len(incomplete) > 0 {
+		t.Fatal("incomplete", incomplete)
+	}
+
+	outer := p.msgs[".a.b.Outer"]
+	if ref := outer.FieldByName("later").Ref; ref == nil || ref.Name != "a.b.Later" {
+		t.Errorf("later = %v", ref)
+	}
+	if ref := outer.FieldByName("other").Ref; ref == nil || ref.Name != "a.c.Other" {
+		t.Errorf("other = %v", ref)
+	}
+	if f := outer.FieldByName("entries"); f.Kind != jsonpb.MapKind || f.Repeated {
+		t.Errorf("entries = %+v", f)
+	}
+}
+
+func makeGroupFieldFile() *descriptorpb.FileDescriptorProto {
+	sopts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(sopts, annotation.E_Server, "test-server")
+	proto.SetExtension(sopts, annotation.E_DefaultHandler, "jsonapi")
+	mopts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(mopts, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/legacy"}})
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("legacy.proto"),
+		Package: proto.String("legacy"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("id"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+					{Name: proto.String("result"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum(), TypeName: proto.String(".legacy.Request.Result")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Result")}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name:    proto.String("LegacyService"),
+				Options: sopts,
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: proto.String("Call"), InputType: proto.String(".legacy.Request"), OutputType: proto.String(".legacy.Request"), Options: mopts},
+				},
+			},
+		},
+	}
+}
+
+func TestDroppedFields(t *testing.T) {
+	p := NewParser()
+	routes, err := p.AddFile(nil, makeGroupFieldFile(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := p.DroppedFields()
+	if len(routes) != 1 || len(dropped) != 1 || dropped[0].Message != "legacy.Request" || dropped[0].Field != "result" {
+		t.Fatal("unexpected dropped fields", dropped)
+	}
+	t.Log(dropped[0].String())
+
+	p = NewParser()
+	p.StrictFields = true
+	_, err = p.AddFile(nil, makeGroupFieldFile(), false)
+	if err == nil {
+		t.Fatal("expect error in strict mode")
+	}
+	t.Log(err)
+}
+
+func TestIgnoreInvalidService(t *testing.T) {
+	fd := makeGroupFieldFile()
+	badOpts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(badOpts, annotation.E_Server, "test-server")
+	proto.SetExtension(badOpts, annotation.E_Use, []string{"bad name"})
+	fd.Service = append(fd.Service,
+		&descriptorpb.ServiceDescriptorProto{Name: proto.String("NoServer")},
+		&descriptorpb.ServiceDescriptorProto{Name: proto.String("BadUse"), Options: badOpts},
+	)
+
+	routes, err := NewParser().AddFile(nil, fd, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Path != "/legacy" {
+		t.Fatalf("routes = %v", routes)
+	}
+}
+
+func TestParseSharedMessage(t *testing.T) {
+	bindField := func(name string, num int32, bind annotation.FIELD_BIND, alias string) *descriptorpb.FieldDescriptorProto {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, annotation.E_Bind, bind)
+		if alias != "" {
+			proto.SetExtension(opts, annotation.E_Alias, alias)
+		}
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(num), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: opts}
+	}
+	fd := makeGroupFieldFile()
+	// 同一个消息既是输入也是输出，只绑定到输入的字段不影响响应
+	fd.MessageType[0].Field = []*descriptorpb.FieldDescriptorProto{
+		bindField("uid", 1, annotation.FIELD_BIND_FROM_CONTEXT, ""),
+		bindField("q", 2, annotation.FIELD_BIND_FROM_QUERY, ""),
+		bindField("etag", 3, annotation.FIELD_BIND_FROM_HEADER, "ETag"),
+	}
+	fd.MessageType[0].NestedType = nil
+
+	p := NewParser()
+	routes, err := p.AddFileAPIRoutes(nil, fd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || len(routes[0].Bindings) != 3 || len(routes[0].ResponseBindings) != 1 || routes[0].ResponseBindings[0].Name != "ETag" {
+		t.Fatalf("routes = %+v", routes)
+	}
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	comment := func(text string, path ...int32) *descriptorpb.SourceCodeInfo_Location {
+		return &descriptorpb.SourceCodeInfo_Location{Path: path, Span: []int32{1, 0, 2}, LeadingComments: proto.String(text)}
+	}
+	fd := makeGroupFieldFile()
+	fd.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			comment(" @host -bad-\n", 6, 0),
+			comment(" @cache immutable\n", 6, 0, 2, 0),
+		},
+	}
+
+	// 只返回 metadata.Route 时注解错误是警告
+	p := NewParser()
+	routes, err := p.AddFile(nil, fd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := p.Diagnostics()
+	if len(routes) != 1 || len(diags) != 2 || diags[0].Severity != apimeta.SeverityWarning || diags[0].Method != "legacy.LegacyService" || diags[1].Message != "invalid cache directive 'immutable'" {
+		t.Fatalf("routes = %v, diagnostics = %v", routes, diags)
+	}
+
+	_, err = NewParser().AddFileAPIRoutes(nil, fd, false)
+	if err == nil || !strings.Contains(err.Error(), "invalid host pattern '-bad-'") {
+		t.Fatalf("AddFileAPIRoutes() error = %v", err)
+	}
+}
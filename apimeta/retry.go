@@ -0,0 +1,55 @@
+package apimeta
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = time.Second
+	DefaultRetryMultiplier = 2
+)
+
+// RetryPolicy 是网关调用后端时的重试策略，通过注释中的 `@retry` 声明，比如：
+//
+//	@retry attempts=3 backoff=100ms max-backoff=1s multiplier=2 codes=UNAVAILABLE,RESOURCE_EXHAUSTED
+//	@retry attempts=3 hedging-delay=50ms codes=UNAVAILABLE
+//
+// 方法没有声明时使用服务的声明，方法可以用 `@retry off` 关闭服务的重试策略。
+// HedgingDelay 大于 0 时使用对冲请求：每隔 HedgingDelay 发出一个新的请求，直到某个请求成功、返回不在 Codes 中的错误或者请求数达到 MaxAttempts，此时不使用退避。
+type RetryPolicy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	HedgingDelay      time.Duration
+	Codes             []codes.Code
+}
+
+func (p *RetryPolicy) Hedging() bool {
+	return p.HedgingDelay > 0
+}
+
+// Retryable 判断返回 code 的请求能否重试
+func (p *RetryPolicy) Retryable(code codes.Code) bool {
+	for _, c := range p.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff 返回第 attempt 次重试（从 1 开始）之前的等待时间，网关应在此基础上增加随机抖动
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt && d < float64(p.MaxBackoff); i++ {
+		d *= p.BackoffMultiplier
+	}
+	if d > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,36 @@
+package apimeta
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		MaxAttempts:       5,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		BackoffMultiplier: 3,
+		Codes:             []codes.Code{codes.Unavailable},
+	}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 300 * time.Millisecond},
+		{attempt: 3, want: 900 * time.Millisecond},
+		{attempt: 4, want: time.Second},
+		{attempt: 100, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+	if !p.Retryable(codes.Unavailable) || p.Retryable(codes.Internal) {
+		t.Error("Retryable() mismatch")
+	}
+}
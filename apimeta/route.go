@@ -0,0 +1,140 @@
+package apimeta
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type SourceLocation struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (l SourceLocation) String() string {
+	if l.Line <= 0 {
+		return l.File
+	}
+	return l.File + ":" + strconv.Itoa(l.Line) + ":" + strconv.Itoa(l.Column)
+}
+
+type ResponseTarget uint8
+
+const (
+	ResponseHeader ResponseTarget = iota
+	ResponseStatus
+)
+
+// StatusBindingName 是绑定到 HTTP 状态码的字段名，输出消息中设置了 `(gapi.bind) = FROM_HEADER` 和 `(gapi.alias) = ":status"` 的整数字段会作为状态码
+const StatusBindingName = ":status"
+
+// ResponseBinding 描述从响应体中移出并设置到 HTTP 响应上的字段
+type ResponseBinding struct {
+	Name   string
+	Kind   jsonpb.Kind
+	Tag    uint32
+	Target ResponseTarget
+}
+
+// FieldBinding 描述从请求参数中提取并写入输入消息的字段。
+// 绑定到 query 的消息类型字段会展开成以 '.' 连接的多个绑定（如 `page.size`），Path 是从输入消息开始逐级的字段 tag；
+// Repeated 为 true 时同名参数的多个值都会写入字段。
+type FieldBinding struct {
+	Name     string
+	Kind     jsonpb.Kind
+	Path     []uint32
+	Repeated bool
+	Bind     metadata.BindSource
+}
+
+// HandlerBinding 表示请求体的媒体类型为 Consumes、客户端接受 Produces 时由 Handler 处理请求，
+// 媒体类型可以使用 `*/*` 或 `type/*` 形式的通配
+type HandlerBinding struct {
+	Consumes string
+	Produces string
+	Handler  string
+}
+
+// Service 是路由所属服务的信息，同一个服务的路由共享同一个 Service
+type Service struct {
+	Name           string
+	File           string
+	Server         string
+	PathPrefix     string
+	DefaultHandler string
+	DefaultTimeout time.Duration
+	Use            []string
+	Hosts          []string
+	Attributes     Attributes
+}
+
+// Route 在 metadata.Route 的基础上附带了方法注释中的文档信息和源码位置，用于网关生成 Deprecation/Sunset 等响应头和路由列表
+type Route struct {
+	*metadata.Route
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	Sunset      time.Time
+	Source      SourceLocation
+	Service     *Service
+	// FieldMask 是客户端传递字段掩码的 query 参数名，为空时路由不支持部分响应，通过方法注释中的 `@fieldmask [param]` 开启
+	FieldMask string
+	// Handlers 是按媒体类型协商的 handler，通过注释中的 `@handler <consumes> <produces> <handler>` 声明，方法没有声明时使用服务的声明
+	Handlers []HandlerBinding
+	// Cache 是路由的缓存策略，没有声明时为 nil
+	Cache *CachePolicy
+	// Retry 是网关调用后端的重试策略，没有声明时为 nil
+	Retry *RetryPolicy
+	// Hosts 是路由绑定的 host 模式，通过注释中的 `@host a.example.com,*.example.com` 声明，方法没有声明时使用服务的声明，为空时匹配所有 host
+	Hosts []string
+	// Attributes 是合并了服务属性的路由自定义属性
+	Attributes Attributes
+	// Bindings 是输入消息的全部参数绑定，Call.Bindings 中只保留了其中的顶层标量字段，展开的消息字段只在这里
+	Bindings []FieldBinding
+	// ResponseBindings 是输出消息中绑定到响应头或状态码的字段，这些字段不会出现在响应体中
+	ResponseBindings []ResponseBinding
+}
+
+func MetadataRoutes(routes []*Route) []*metadata.Route {
+	mroutes := make([]*metadata.Route, 0, len(routes))
+	for _, r := range routes {
+		mroutes = append(mroutes, r.Route)
+	}
+	return mroutes
+}
+
+// DroppedField 描述因为无法转换成 JSON 而没有出现在路由消息中的字段
+type DroppedField struct {
+	Message string
+	Field   string
+	Type    descriptorpb.FieldDescriptorProto_Type
+	Reason  string
+}
+
+func (f *DroppedField) String() string {
+	return f.Message + "." + f.Field + " (" + f.Type.String() + "): " + f.Reason
+}
+
+// CheckMiddlewareName 检查中间件和 handler 的名字，只允许字母、数字、下划线和连字符
+func CheckMiddlewareName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if 'a' <= c && c <= 'z' ||
+			'A' <= c && c <= 'Z' ||
+			'0' <= c && c <= '9' ||
+			c == '_' || c == '-' {
+			continue
+		}
+		return false
+	}
+	return true
+}
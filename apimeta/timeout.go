@@ -0,0 +1,62 @@
+package apimeta
+
+import (
+	"time"
+)
+
+// TimeoutPolicy 约束路由的超时时间。
+// 方法没有设置超时时依次使用服务的 default_timeout、ServerDefaults[server] 和 Default，都没有设置时视为不限制超时。
+type TimeoutPolicy struct {
+	Default        time.Duration
+	ServerDefaults map[string]time.Duration
+	Min            time.Duration
+	Max            time.Duration
+	// Clamp 为 true 时把超出 [Min, Max] 的超时截断到边界，否则拒绝该方法
+	Clamp bool
+}
+
+// Apply 对已经回退到服务 default_timeout 的 timeout 应用策略，返回最终的超时时间。
+// 发生截断时返回 SeverityWarning 的诊断，拒绝时返回 SeverityError 的诊断，诊断的 Method 和 Source 由调用方填充。
+func (p *TimeoutPolicy) Apply(server string, timeout time.Duration) (time.Duration, *Diagnostic) {
+	if timeout < 0 {
+		return 0, &Diagnostic{
+			Severity: SeverityError,
+			Message:  "negative timeout " + timeout.String(),
+		}
+	}
+	if timeout == 0 {
+		timeout = p.ServerDefaults[server]
+	}
+	if timeout == 0 {
+		timeout = p.Default
+	}
+
+	var (
+		bound   time.Duration
+		message string
+	)
+	switch {
+	case p.Max > 0 && timeout == 0:
+		bound = p.Max
+		message = "unlimited timeout exceeds maximum " + p.Max.String()
+	case p.Max > 0 && timeout > p.Max:
+		bound = p.Max
+		message = "timeout " + timeout.String() + " exceeds maximum " + p.Max.String()
+	case p.Min > 0 && timeout > 0 && timeout < p.Min:
+		bound = p.Min
+		message = "timeout " + timeout.String() + " is less than minimum " + p.Min.String()
+	default:
+		return timeout, nil
+	}
+
+	if p.Clamp {
+		return bound, &Diagnostic{
+			Severity: SeverityWarning,
+			Message:  message + ", clamped to " + bound.String(),
+		}
+	}
+	return 0, &Diagnostic{
+		Severity: SeverityError,
+		Message:  message,
+	}
+}
@@ -0,0 +1,51 @@
+package apimeta
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutPolicy_Apply(t *testing.T) {
+	policy := TimeoutPolicy{
+		Default:        3 * time.Second,
+		ServerDefaults: map[string]time.Duration{"slow": 20 * time.Second},
+		Min:            100 * time.Millisecond,
+		Max:            10 * time.Second,
+	}
+	clamp := policy
+	clamp.Clamp = true
+	unlimited := TimeoutPolicy{Max: 10 * time.Second, Clamp: true}
+
+	tests := []struct {
+		name     string
+		policy   *TimeoutPolicy
+		server   string
+		timeout  time.Duration
+		want     time.Duration
+		severity Severity
+		diag     bool
+	}{
+		{name: "keep", policy: &policy, timeout: 5 * time.Second, want: 5 * time.Second},
+		{name: "default", policy: &policy, want: 3 * time.Second},
+		{name: "server_default", policy: &clamp, server: "slow", want: 10 * time.Second, diag: true, severity: SeverityWarning},
+		{name: "negative", policy: &clamp, timeout: -1, diag: true, severity: SeverityError},
+		{name: "reject_max", policy: &policy, timeout: 10 * time.Minute, diag: true, severity: SeverityError},
+		{name: "clamp_max", policy: &clamp, timeout: 10 * time.Minute, want: 10 * time.Second, diag: true, severity: SeverityWarning},
+		{name: "clamp_min", policy: &clamp, timeout: time.Millisecond, want: 100 * time.Millisecond, diag: true, severity: SeverityWarning},
+		{name: "unlimited", policy: &unlimited, want: 10 * time.Second, diag: true, severity: SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diag := tt.policy.Apply(tt.server, tt.timeout)
+			if got != tt.want {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+			if (diag != nil) != tt.diag {
+				t.Fatalf("Apply() diag = %v, want %v", diag, tt.diag)
+			}
+			if diag != nil && diag.Severity != tt.severity {
+				t.Errorf("Apply() severity = %v, want %v", diag.Severity, tt.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/vizee/gapi-plus/httpcache
+
+go 1.20
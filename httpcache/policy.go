@@ -0,0 +1,120 @@
+package httpcache
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy 是路由的 HTTP 缓存策略，通过注释中的 `@cache` 声明，比如：
+//
+//	@cache public max-age=60 vary=Accept-Language vary-query=page,size etag
+//
+// 方法没有声明时使用服务的声明，方法可以用 `@cache no-store` 关闭服务的缓存策略。
+type Policy struct {
+	MaxAge  time.Duration
+	Private bool
+	NoStore bool
+	// ETag 为 true 时网关根据响应体生成 ETag 并处理 If-None-Match
+	ETag        bool
+	VaryHeaders []string
+	VaryQuery   []string
+}
+
+// CacheControl 返回 Cache-Control 响应头的值
+func (p *Policy) CacheControl() string {
+	if p.NoStore {
+		return "no-store"
+	}
+	var b strings.Builder
+	if p.Private {
+		b.WriteString("private")
+	} else {
+		b.WriteString("public")
+	}
+	b.WriteString(", max-age=")
+	b.WriteString(strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+	return b.String()
+}
+
+// Cacheable 判断网关能否在共享缓存中保存 method 请求的响应，只有 GET 和 HEAD 请求可以缓存
+func (p *Policy) Cacheable(method string) bool {
+	return (method == http.MethodGet || method == http.MethodHead) && !p.NoStore && !p.Private && p.MaxAge > 0
+}
+
+// Key 根据 VaryHeaders 和 VaryQuery 生成缓存键，没有列出的请求头和 query 参数不影响缓存键
+func (p *Policy) Key(path string, header http.Header, query url.Values) string {
+	var b strings.Builder
+	b.WriteString(path)
+	for _, name := range p.VaryQuery {
+		for _, v := range query[name] {
+			b.WriteByte('\x00')
+			b.WriteString(name)
+			b.WriteByte('=')
+			b.WriteString(v)
+		}
+	}
+	for _, name := range p.VaryHeaders {
+		b.WriteByte('\x00')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func parseMaxAge(s string) (time.Duration, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid max-age '" + s + "'")
+	}
+	return d, nil
+}
+
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParsePolicy 解析 `@cache` 注解，max-age 可以是秒数或者 time.Duration 格式
+func ParsePolicy(line string) (*Policy, error) {
+	policy := &Policy{}
+	for _, item := range strings.Fields(line) {
+		key, value, _ := strings.Cut(item, "=")
+		switch strings.ToLower(key) {
+		case "public":
+			policy.Private = false
+		case "private":
+			policy.Private = true
+		case "no-store":
+			policy.NoStore = true
+		case "etag":
+			policy.ETag = true
+		case "max-age":
+			d, err := parseMaxAge(value)
+			if err != nil {
+				return nil, err
+			}
+			policy.MaxAge = d
+		case "vary":
+			policy.VaryHeaders = append(policy.VaryHeaders, splitNames(value)...)
+		case "vary-query":
+			policy.VaryQuery = append(policy.VaryQuery, splitNames(value)...)
+		default:
+			return nil, errors.New("invalid cache directive '" + item + "'")
+		}
+	}
+	return policy, nil
+}
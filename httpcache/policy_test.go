@@ -0,0 +1,87 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       Policy
+		method       string
+		cacheControl string
+		cacheable    bool
+	}{
+		{name: "public", policy: Policy{MaxAge: time.Minute}, method: "GET", cacheControl: "public, max-age=60", cacheable: true},
+		{name: "post", policy: Policy{MaxAge: time.Minute}, method: "POST", cacheControl: "public, max-age=60", cacheable: false},
+		{name: "private", policy: Policy{MaxAge: 90 * time.Second, Private: true}, method: "GET", cacheControl: "private, max-age=90", cacheable: false},
+		{name: "no_store", policy: Policy{MaxAge: time.Minute, NoStore: true}, method: "GET", cacheControl: "no-store", cacheable: false},
+		{name: "zero", policy: Policy{ETag: true}, method: "HEAD", cacheControl: "public, max-age=0", cacheable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.CacheControl(); got != tt.cacheControl {
+				t.Errorf("CacheControl() = %q, want %q", got, tt.cacheControl)
+			}
+			if got := tt.policy.Cacheable(tt.method); got != tt.cacheable {
+				t.Errorf("Cacheable() = %v, want %v", got, tt.cacheable)
+			}
+		})
+	}
+}
+
+func TestPolicyKey(t *testing.T) {
+	p := &Policy{VaryHeaders: []string{"Accept-Language"}, VaryQuery: []string{"page"}}
+	key := func(lang string, query string) string {
+		q, _ := url.ParseQuery(query)
+		h := http.Header{}
+		if lang != "" {
+			h.Set("Accept-Language", lang)
+		}
+		h.Set("X-Request-Id", query)
+		return p.Key("/users", h, q)
+	}
+	if key("zh", "page=1&t=1") != key("zh", "page=1&t=2") {
+		t.Error("unlisted query changes key")
+	}
+	if key("zh", "page=1") == key("zh", "page=2") {
+		t.Error("vary query not in key")
+	}
+	if key("zh", "page=1") == key("en", "page=1") {
+		t.Error("vary header not in key")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    *Policy
+		wantErr bool
+	}{
+		{line: "public max-age=60", want: &Policy{MaxAge: time.Minute}},
+		{line: "private max-age=5m etag", want: &Policy{MaxAge: 5 * time.Minute, Private: true, ETag: true}},
+		{line: "no-store", want: &Policy{NoStore: true}},
+		{
+			line: "max-age=30 vary=Accept-Language,X-Tenant vary-query=page,size",
+			want: &Policy{MaxAge: 30 * time.Second, VaryHeaders: []string{"Accept-Language", "X-Tenant"}, VaryQuery: []string{"page", "size"}},
+		},
+		{line: "max-age=-1", wantErr: true},
+		{line: "max-age=forever", wantErr: true},
+		{line: "immutable", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := ParsePolicy(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
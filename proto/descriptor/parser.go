@@ -13,6 +13,8 @@ type Parser struct {
 	prefix string
 	msgs   map[string]*MessageDesc
 	svcs   []*ServiceDesc
+	file   string
+	locs   map[string]*descriptorpb.SourceCodeInfo_Location
 }
 
 func NewParser() *Parser {
@@ -38,6 +40,21 @@ func normalName(name string) string {
 	return name
 }
 
+func (p *Parser) getSourceInfo(path ...int32) SourceInfo {
+	si := SourceInfo{
+		File: p.file,
+	}
+	loc := p.locs[SourcePathKey(path)]
+	if loc != nil {
+		if len(loc.Span) >= 2 {
+			si.Line = int(loc.Span[0]) + 1
+			si.Column = int(loc.Span[1]) + 1
+		}
+		si.Comments = loc.GetLeadingComments()
+	}
+	return si
+}
+
 func (p *Parser) getMessage(fullName string) *MessageDesc {
 	msg := p.msgs[fullName]
 	if msg == nil {
@@ -94,38 +111,35 @@ func (p *Parser) parseMessage(md *descriptorpb.DescriptorProto) {
 	msg.Incomplete = false
 }
 
-func (p *Parser) parseMethod(md *descriptorpb.MethodDescriptorProto) (*MethodDesc, error) {
+// HttpRule 返回 gapi.http 选项中的 HTTP 方法和路径，没有设置时返回空字符串
+func HttpRule(opt *annotation.Http) (string, string) {
+	switch t := opt.GetPattern().(type) {
+	case *annotation.Http_Get:
+		return "GET", t.Get
+	case *annotation.Http_Post:
+		return "POST", t.Post
+	case *annotation.Http_Put:
+		return "PUT", t.Put
+	case *annotation.Http_Delete:
+		return "DELETE", t.Delete
+	case *annotation.Http_Patch:
+		return "PATCH", t.Patch
+	case *annotation.Http_Custom:
+		return t.Custom.GetMethod(), t.Custom.GetPath()
+	}
+	return "", ""
+}
+
+func (p *Parser) parseMethod(md *descriptorpb.MethodDescriptorProto, source SourceInfo) (*MethodDesc, error) {
 	m := &MethodDesc{
 		Name:      md.GetName(),
 		In:        p.getMessage(md.GetInputType()),
 		Out:       p.getMessage(md.GetOutputType()),
 		Streaming: md.GetClientStreaming() || md.GetServerStreaming(),
+		Source:    source,
 	}
 	if opts, ok := proto.GetExtension(md.Options, annotation.E_Http).(*annotation.Http); ok && opts != nil {
-		var (
-			method string
-			path   string
-		)
-		switch t := opts.Pattern.(type) {
-		case *annotation.Http_Get:
-			method = "GET"
-			path = t.Get
-		case *annotation.Http_Post:
-			method = "POST"
-			path = t.Post
-		case *annotation.Http_Put:
-			method = "PUT"
-			path = t.Put
-		case *annotation.Http_Delete:
-			method = "DELETE"
-			path = t.Delete
-		case *annotation.Http_Patch:
-			method = "PATCH"
-			path = t.Patch
-		case *annotation.Http_Custom:
-			method = t.Custom.Method
-			path = t.Custom.Path
-		}
+		method, path := HttpRule(opts)
 		m.Opts = MethodOptions{
 			Method:  method,
 			Path:    path,
@@ -137,7 +151,7 @@ func (p *Parser) parseMethod(md *descriptorpb.MethodDescriptorProto) (*MethodDes
 	return m, nil
 }
 
-func (p *Parser) parseService(sd *descriptorpb.ServiceDescriptorProto) error {
+func (p *Parser) parseService(sd *descriptorpb.ServiceDescriptorProto, index int32) error {
 	use, _ := proto.GetExtension(sd.Options, annotation.E_Use).([]string)
 	svc := &ServiceDesc{
 		Name:     sd.GetName(),
@@ -149,9 +163,10 @@ func (p *Parser) parseService(sd *descriptorpb.ServiceDescriptorProto) error {
 			PathPrefix:     getOption(proto.GetExtension(sd.Options, annotation.E_PathPrefix), ""),
 			Use:            use,
 		},
+		Source: p.getSourceInfo(ServicePath(index)...),
 	}
-	for _, md := range sd.Method {
-		method, err := p.parseMethod(md)
+	for i, md := range sd.Method {
+		method, err := p.parseMethod(md, p.getSourceInfo(MethodPath(index, int32(i))...))
 		if err != nil {
 			return err
 		}
@@ -165,12 +180,18 @@ func (p *Parser) AddFile(fd *descriptorpb.FileDescriptorProto) error {
 	p.enter(fd.GetPackage())
 	defer p.leave()
 
+	p.file = fd.GetName()
+	p.locs = make(map[string]*descriptorpb.SourceCodeInfo_Location, len(fd.GetSourceCodeInfo().GetLocation()))
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		p.locs[SourcePathKey(loc.Path)] = loc
+	}
+
 	for _, dp := range fd.MessageType {
 		p.parseMessage(dp)
 	}
 
-	for _, sdp := range fd.Service {
-		err := p.parseService(sdp)
+	for i, sdp := range fd.Service {
+		err := p.parseService(sdp, int32(i))
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,35 @@
+package descriptor
+
+import (
+	"strconv"
+)
+
+// SourceCodeInfo.Location.path 中用到的字段编号
+const (
+	// FileServiceField 是 FileDescriptorProto.service 的字段编号
+	FileServiceField int32 = 6
+	// ServiceMethodField 是 ServiceDescriptorProto.method 的字段编号
+	ServiceMethodField int32 = 2
+)
+
+// ServicePath 返回文件中第 service 个服务的源码位置路径
+func ServicePath(service int32) []int32 {
+	return []int32{FileServiceField, service}
+}
+
+// MethodPath 返回文件中第 service 个服务的第 method 个方法的源码位置路径
+func MethodPath(service int32, method int32) []int32 {
+	return []int32{FileServiceField, service, ServiceMethodField, method}
+}
+
+// SourcePathKey 把源码位置路径转换成字符串，用作查找 SourceCodeInfo.Location 的 key
+func SourcePathKey(path []int32) string {
+	var buf []byte
+	for i, v := range path {
+		if i > 0 {
+			buf = append(buf, '.')
+		}
+		buf = strconv.AppendInt(buf, int64(v), 10)
+	}
+	return string(buf)
+}
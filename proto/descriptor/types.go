@@ -23,6 +23,14 @@ type MessageDesc struct {
 	Incomplete bool
 }
 
+// SourceInfo 记录描述符在源文件中的位置和注释，Line 和 Column 从 1 开始，为 0 时表示没有源码信息
+type SourceInfo struct {
+	File     string
+	Line     int
+	Column   int
+	Comments string
+}
+
 type ServiceOptions struct {
 	Server         string
 	DefaultHandler string
@@ -36,6 +44,7 @@ type ServiceDesc struct {
 	FullName string
 	Methods  []*MethodDesc
 	Opts     ServiceOptions
+	Source   SourceInfo
 }
 
 type MethodDesc struct {
@@ -44,6 +53,7 @@ type MethodDesc struct {
 	Out       *MessageDesc
 	Streaming bool
 	Opts      MethodOptions
+	Source    SourceInfo
 }
 
 type MethodOptions struct {
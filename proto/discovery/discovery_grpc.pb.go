@@ -0,0 +1,176 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: discovery.proto
+
+package discovery
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GapiDescriptor_GetDescriptors_FullMethodName   = "/gapi.plus.discovery.GapiDescriptor/GetDescriptors"
+	GapiDescriptor_WatchDescriptors_FullMethodName = "/gapi.plus.discovery.GapiDescriptor/WatchDescriptors"
+)
+
+// GapiDescriptorClient is the client API for GapiDescriptor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GapiDescriptorClient interface {
+	GetDescriptors(ctx context.Context, in *GetDescriptorsRequest, opts ...grpc.CallOption) (*GetDescriptorsResponse, error)
+	// WatchDescriptors 先返回一次当前的描述符，之后每次描述符变化时返回新的描述符
+	WatchDescriptors(ctx context.Context, in *WatchDescriptorsRequest, opts ...grpc.CallOption) (GapiDescriptor_WatchDescriptorsClient, error)
+}
+
+type gapiDescriptorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGapiDescriptorClient(cc grpc.ClientConnInterface) GapiDescriptorClient {
+	return &gapiDescriptorClient{cc}
+}
+
+func (c *gapiDescriptorClient) GetDescriptors(ctx context.Context, in *GetDescriptorsRequest, opts ...grpc.CallOption) (*GetDescriptorsResponse, error) {
+	out := new(GetDescriptorsResponse)
+	err := c.cc.Invoke(ctx, GapiDescriptor_GetDescriptors_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gapiDescriptorClient) WatchDescriptors(ctx context.Context, in *WatchDescriptorsRequest, opts ...grpc.CallOption) (GapiDescriptor_WatchDescriptorsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GapiDescriptor_ServiceDesc.Streams[0], GapiDescriptor_WatchDescriptors_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gapiDescriptorWatchDescriptorsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GapiDescriptor_WatchDescriptorsClient interface {
+	Recv() (*GetDescriptorsResponse, error)
+	grpc.ClientStream
+}
+
+type gapiDescriptorWatchDescriptorsClient struct {
+	grpc.ClientStream
+}
+
+func (x *gapiDescriptorWatchDescriptorsClient) Recv() (*GetDescriptorsResponse, error) {
+	m := new(GetDescriptorsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GapiDescriptorServer is the server API for GapiDescriptor service.
+// All implementations must embed UnimplementedGapiDescriptorServer
+// for forward compatibility
+type GapiDescriptorServer interface {
+	GetDescriptors(context.Context, *GetDescriptorsRequest) (*GetDescriptorsResponse, error)
+	// WatchDescriptors 先返回一次当前的描述符，之后每次描述符变化时返回新的描述符
+	WatchDescriptors(*WatchDescriptorsRequest, GapiDescriptor_WatchDescriptorsServer) error
+	mustEmbedUnimplementedGapiDescriptorServer()
+}
+
+// UnimplementedGapiDescriptorServer must be embedded to have forward compatible implementations.
+type UnimplementedGapiDescriptorServer struct {
+}
+
+func (UnimplementedGapiDescriptorServer) GetDescriptors(context.Context, *GetDescriptorsRequest) (*GetDescriptorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDescriptors not implemented")
+}
+func (UnimplementedGapiDescriptorServer) WatchDescriptors(*WatchDescriptorsRequest, GapiDescriptor_WatchDescriptorsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDescriptors not implemented")
+}
+func (UnimplementedGapiDescriptorServer) mustEmbedUnimplementedGapiDescriptorServer() {}
+
+// UnsafeGapiDescriptorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GapiDescriptorServer will
+// result in compilation errors.
+type UnsafeGapiDescriptorServer interface {
+	mustEmbedUnimplementedGapiDescriptorServer()
+}
+
+func RegisterGapiDescriptorServer(s grpc.ServiceRegistrar, srv GapiDescriptorServer) {
+	s.RegisterService(&GapiDescriptor_ServiceDesc, srv)
+}
+
+func _GapiDescriptor_GetDescriptors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDescriptorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GapiDescriptorServer).GetDescriptors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GapiDescriptor_GetDescriptors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GapiDescriptorServer).GetDescriptors(ctx, req.(*GetDescriptorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GapiDescriptor_WatchDescriptors_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDescriptorsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GapiDescriptorServer).WatchDescriptors(m, &gapiDescriptorWatchDescriptorsServer{stream})
+}
+
+type GapiDescriptor_WatchDescriptorsServer interface {
+	Send(*GetDescriptorsResponse) error
+	grpc.ServerStream
+}
+
+type gapiDescriptorWatchDescriptorsServer struct {
+	grpc.ServerStream
+}
+
+func (x *gapiDescriptorWatchDescriptorsServer) Send(m *GetDescriptorsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// GapiDescriptor_ServiceDesc is the grpc.ServiceDesc for GapiDescriptor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GapiDescriptor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gapi.plus.discovery.GapiDescriptor",
+	HandlerType: (*GapiDescriptorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetDescriptors",
+			Handler:    _GapiDescriptor_GetDescriptors_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDescriptors",
+			Handler:       _GapiDescriptor_WatchDescriptors_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "discovery.proto",
+}
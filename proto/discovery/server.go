@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"sync"
+
+	"github.com/vizee/gapi-plus/proto/reflection"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Server 实现 GapiDescriptor 服务，描述符在第一次请求时收集，之后通过 Refresh 更新
+type Server struct {
+	UnimplementedGapiDescriptorServer
+
+	collect func() (*descriptorpb.FileDescriptorSet, error)
+
+	mu       sync.Mutex
+	files    *descriptorpb.FileDescriptorSet
+	checksum string
+	changed  chan struct{}
+}
+
+func NewServer(collect func() (*descriptorpb.FileDescriptorSet, error)) *Server {
+	return &Server{
+		collect: collect,
+		changed: make(chan struct{}),
+	}
+}
+
+// Register 在 srv 上注册 GapiDescriptor 服务，返回的描述符来自 srv 上注册的所有服务
+func Register(srv *grpc.Server, opts ...reflection.Option) *Server {
+	s := NewServer(func() (*descriptorpb.FileDescriptorSet, error) {
+		return reflection.CollectGapiFiles(srv, opts...)
+	})
+	RegisterGapiDescriptorServer(srv, s)
+	return s
+}
+
+// Checksum 计算描述符集合的 sha1 校验和
+func Checksum(files *descriptorpb.FileDescriptorSet) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(files)
+	if err != nil {
+		return "", err
+	}
+	sha1sum := sha1.Sum(data)
+	return hex.EncodeToString(sha1sum[:]), nil
+}
+
+func (s *Server) load() (*descriptorpb.FileDescriptorSet, string, <-chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		files, err := s.collect()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		checksum, err := Checksum(files)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		s.files = files
+		s.checksum = checksum
+	}
+	return s.files, s.checksum, s.changed, nil
+}
+
+// Refresh 重新收集描述符，描述符发生变化时通知所有 watcher 并返回 true
+func (s *Server) Refresh() (bool, error) {
+	files, err := s.collect()
+	if err != nil {
+		return false, err
+	}
+	checksum, err := Checksum(files)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files != nil && s.checksum == checksum {
+		return false, nil
+	}
+	s.files = files
+	s.checksum = checksum
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return true, nil
+}
+
+func (s *Server) GetDescriptors(ctx context.Context, req *GetDescriptorsRequest) (*GetDescriptorsResponse, error) {
+	files, checksum, _, err := s.load()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if req.KnownChecksum == checksum {
+		return &GetDescriptorsResponse{
+			Checksum:    checksum,
+			NotModified: true,
+		}, nil
+	}
+	return &GetDescriptorsResponse{
+		Checksum: checksum,
+		Files:    files,
+	}, nil
+}
+
+func (s *Server) WatchDescriptors(req *WatchDescriptorsRequest, stream GapiDescriptor_WatchDescriptorsServer) error {
+	known := req.KnownChecksum
+	for {
+		files, checksum, changed, err := s.load()
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if checksum != known {
+			err := stream.Send(&GetDescriptorsResponse{
+				Checksum: checksum,
+				Files:    files,
+			})
+			if err != nil {
+				return err
+			}
+			known = checksum
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
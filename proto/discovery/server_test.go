@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestServer(t *testing.T) {
+	version := "v1"
+	collects := 0
+	s := NewServer(func() (*descriptorpb.FileDescriptorSet, error) {
+		collects++
+		return &descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{{
+				Name:    proto.String("api.proto"),
+				Package: proto.String("api." + version),
+			}},
+		}, nil
+	})
+
+	srv := grpc.NewServer()
+	RegisterGapiDescriptorServer(srv, s)
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewGapiDescriptorClient(conn)
+
+	resp, err := client.GetDescriptors(ctx, &GetDescriptorsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.NotModified || len(resp.Files.GetFile()) != 1 || resp.Files.File[0].GetPackage() != "api.v1" {
+		t.Fatalf("GetDescriptors() = %v", resp)
+	}
+	v1 := resp.Checksum
+	resp, err = client.GetDescriptors(ctx, &GetDescriptorsRequest{KnownChecksum: v1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.NotModified || resp.Files != nil || resp.Checksum != v1 {
+		t.Fatalf("GetDescriptors(known) = %v", resp)
+	}
+
+	if changed, err := s.Refresh(); err != nil || changed {
+		t.Fatalf("Refresh() = %v, %v", changed, err)
+	}
+
+	stream, err := client.WatchDescriptors(ctx, &WatchDescriptorsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Checksum != v1 {
+		t.Fatalf("WatchDescriptors() = %v", resp)
+	}
+
+	version = "v2"
+	if changed, err := s.Refresh(); err != nil || !changed {
+		t.Fatalf("Refresh() = %v, %v", changed, err)
+	}
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Checksum == v1 || resp.Files.File[0].GetPackage() != "api.v2" {
+		t.Fatalf("WatchDescriptors() = %v", resp)
+	}
+	if collects != 3 {
+		t.Fatalf("collects = %d", collects)
+	}
+}
@@ -0,0 +1,86 @@
+package plugintest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Request 读取 protoc 输出的 FileDescriptorSet，返回生成 generate 中文件的插件请求。
+// testdata/pdtest/pdtest.pd 没有包含依赖的 descriptor.proto 和 gapi/annotation.proto，缺少时补充在最前面
+func Request(t testing.TB, descriptorSet string, generate ...string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	data, err := os.ReadFile(descriptorSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fds descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]bool)
+	for _, fd := range fds.File {
+		found[fd.GetName()] = true
+	}
+	var files []*descriptorpb.FileDescriptorProto
+	for _, fd := range []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+	} {
+		if !found[fd.GetName()] {
+			files = append(files, fd)
+		}
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: generate,
+		ProtoFile:      append(files, fds.File...),
+	}
+}
+
+// File 返回 req 中名为 name 的文件，测试可以直接修改它构造错误的输入
+func File(t testing.TB, req *pluginpb.CodeGeneratorRequest, name string) *descriptorpb.FileDescriptorProto {
+	t.Helper()
+	for _, fd := range req.ProtoFile {
+		if fd.GetName() == name {
+			return fd
+		}
+	}
+	t.Fatalf("missing file %s", name)
+	return nil
+}
+
+// Location 返回 fd 中 path 对应的源码位置，测试可以修改其中的注释
+func Location(t testing.TB, fd *descriptorpb.FileDescriptorProto, path ...int32) *descriptorpb.SourceCodeInfo_Location {
+	t.Helper()
+	key := descriptor.SourcePathKey(path)
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		if descriptor.SourcePathKey(loc.Path) == key {
+			return loc
+		}
+	}
+	t.Fatalf("missing location %s in %s", key, fd.GetName())
+	return nil
+}
+
+// Run 使用 req 创建插件并调用 run，run 返回错误时直接返回，否则返回插件的响应
+func Run(t testing.TB, req *pluginpb.CodeGeneratorRequest, run func(plugin *protogen.Plugin) error) (*pluginpb.CodeGeneratorResponse, error) {
+	t.Helper()
+	plugin, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(plugin)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.Response(), nil
+}
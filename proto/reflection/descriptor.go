@@ -1,6 +1,7 @@
 package reflection
 
 import (
+	"fmt"
 	"sort"
 
 	annotation "github.com/vizee/gapi-proto-go/gapi"
@@ -26,23 +27,134 @@ func CollectFileSet(fds *descriptorpb.FileDescriptorSet, visit map[string]bool,
 	}
 }
 
-func CollectServerFiles(srv *grpc.Server, filter func(path string) bool) (*descriptorpb.FileDescriptorSet, error) {
+// ServiceInfoProvider 提供已注册的服务，*grpc.Server 实现了这个接口
+type ServiceInfoProvider interface {
+	GetServiceInfo() map[string]grpc.ServiceInfo
+}
+
+// ServiceNames 把服务全名列表作为 ServiceInfoProvider 使用
+type ServiceNames []string
+
+func (names ServiceNames) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := make(map[string]grpc.ServiceInfo, len(names))
+	for _, name := range names {
+		info[name] = grpc.ServiceInfo{}
+	}
+	return info
+}
+
+// DescriptorResolver 按全名查找描述符，*protoregistry.Files 实现了这个接口
+type DescriptorResolver interface {
+	FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+type collectOptions struct {
+	resolver          DescriptorResolver
+	serviceFilters    []func(sd protoreflect.ServiceDescriptor) bool
+	methodFilters     []func(md protoreflect.MethodDescriptor) bool
+	serviceTransforms []func(sd *descriptorpb.ServiceDescriptorProto) error
+	fileTransforms    []func(fd *descriptorpb.FileDescriptorProto) error
+}
+
+type Option func(o *collectOptions)
+
+// WithResolver 指定查找服务描述符的 resolver，默认使用 protoregistry.GlobalFiles
+func WithResolver(resolver DescriptorResolver) Option {
+	return func(o *collectOptions) {
+		o.resolver = resolver
+	}
+}
+
+// WithServiceFilter 只收集 filter 返回 true 的服务，多个 filter 需要同时满足
+func WithServiceFilter(filter func(sd protoreflect.ServiceDescriptor) bool) Option {
+	return func(o *collectOptions) {
+		o.serviceFilters = append(o.serviceFilters, filter)
+	}
+}
+
+// WithMethodFilter 只收集 filter 返回 true 的方法，方法全部被过滤的服务不会被收集，仅对 CollectGapiFiles 有效
+func WithMethodFilter(filter func(md protoreflect.MethodDescriptor) bool) Option {
+	return func(o *collectOptions) {
+		o.methodFilters = append(o.methodFilters, filter)
+	}
+}
+
+// WithServiceTransform 在服务加入文件前修改服务描述符，仅对 CollectGapiFiles 有效
+func WithServiceTransform(transform func(sd *descriptorpb.ServiceDescriptorProto) error) Option {
+	return func(o *collectOptions) {
+		o.serviceTransforms = append(o.serviceTransforms, transform)
+	}
+}
+
+// WithFileTransform 在文件加入集合前修改文件描述符，仅对 CollectGapiFiles 有效
+func WithFileTransform(transform func(fd *descriptorpb.FileDescriptorProto) error) Option {
+	return func(o *collectOptions) {
+		o.fileTransforms = append(o.fileTransforms, transform)
+	}
+}
+
+func (o *collectOptions) acceptService(sd protoreflect.ServiceDescriptor) bool {
+	for _, filter := range o.serviceFilters {
+		if !filter(sd) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *collectOptions) acceptMethod(md protoreflect.MethodDescriptor) bool {
+	for _, filter := range o.methodFilters {
+		if !filter(md) {
+			return false
+		}
+	}
+	return true
+}
+
+func applyOptions(opts []Option) *collectOptions {
+	o := &collectOptions{
+		resolver: protoregistry.GlobalFiles,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func sortedServiceNames(srv ServiceInfoProvider) []string {
 	serviceInfo := srv.GetServiceInfo()
 	svcNames := make([]string, 0, len(serviceInfo))
 	for name := range serviceInfo {
 		svcNames = append(svcNames, name)
 	}
 	sort.Strings(svcNames)
+	return svcNames
+}
+
+func findService(resolver DescriptorResolver, name string) (protoreflect.ServiceDescriptor, error) {
+	d, err := resolver.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+	return sd, nil
+}
+
+func CollectServerFiles(srv ServiceInfoProvider, filter func(path string) bool, opts ...Option) (*descriptorpb.FileDescriptorSet, error) {
+	o := applyOptions(opts)
 
 	fds := &descriptorpb.FileDescriptorSet{}
 	visit := make(map[string]bool)
-	for _, name := range svcNames {
-		sd, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	for _, name := range sortedServiceNames(srv) {
+		sd, err := findService(o.resolver, name)
 		if err != nil {
 			return nil, err
 		}
 		fd := sd.ParentFile()
-		if !filter(fd.Path()) {
+		if !o.acceptService(sd) || !filter(fd.Path()) {
 			continue
 		}
 		CollectFileSet(fds, visit, fd)
@@ -51,10 +163,14 @@ func CollectServerFiles(srv *grpc.Server, filter func(path string) bool) (*descr
 }
 
 type gapiServiceResolver struct {
+	opts     *collectOptions
 	files    map[string]*descriptorpb.FileDescriptorProto
 	fds      []*descriptorpb.FileDescriptorProto
+	origins  map[string]protoreflect.FileDescriptor
 	visit    map[protoreflect.FullName]bool
 	apiFiles map[string]bool
+	// deps 记录每个文件实际引用了哪些文件，用于裁剪 import
+	deps map[string]map[string]bool
 }
 
 func (c *gapiServiceResolver) getFile(d protoreflect.Descriptor) *descriptorpb.FileDescriptorProto {
@@ -64,43 +180,101 @@ func (c *gapiServiceResolver) getFile(d protoreflect.Descriptor) *descriptorpb.F
 		fd = newFileDescriptor(f)
 		c.files[f.Path()] = fd
 		c.fds = append(c.fds, fd)
+		c.origins[f.Path()] = f
 	}
 	return fd
 }
 
-func (c *gapiServiceResolver) resolveMessage(md protoreflect.MessageDescriptor) {
-	msgName := md.FullName()
-	if c.visit[msgName] {
+func (c *gapiServiceResolver) addDependency(file string, d protoreflect.Descriptor) {
+	dep := d.ParentFile().Path()
+	if dep == file {
 		return
 	}
+	deps := c.deps[file]
+	if deps == nil {
+		deps = make(map[string]bool)
+		c.deps[file] = deps
+	}
+	deps[dep] = true
+}
 
-	c.visit[msgName] = true
-
-	// 过滤嵌套类型
-	nested := md.Messages()
-	for i := 0; i < nested.Len(); i++ {
-		c.visit[nested.Get(i).FullName()] = true
+// topLevelMessage 返回嵌套类型所在的顶层消息，d 本身是顶层类型时返回 nil
+func topLevelMessage(d protoreflect.Descriptor) protoreflect.MessageDescriptor {
+	var top protoreflect.MessageDescriptor
+	for p := d.Parent(); p != nil; p = p.Parent() {
+		if md, ok := p.(protoreflect.MessageDescriptor); ok {
+			top = md
+		}
 	}
+	return top
+}
 
+// resolveFields 解析 md 和它的嵌套消息中字段引用的类型
+func (c *gapiServiceResolver) resolveFields(file string, md protoreflect.MessageDescriptor) {
 	fields := md.Fields()
 	for i := 0; i < fields.Len(); i++ {
 		field := fields.Get(i)
-		if field.Kind() == protoreflect.MessageKind {
+		switch field.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
 			c.resolveMessage(field.Message())
+			c.addDependency(file, field.Message())
+		case protoreflect.EnumKind:
+			c.resolveEnum(field.Enum())
+			c.addDependency(file, field.Enum())
 		}
 	}
+	nested := md.Messages()
+	for i := 0; i < nested.Len(); i++ {
+		c.resolveFields(file, nested.Get(i))
+	}
+}
+
+func (c *gapiServiceResolver) resolveMessage(md protoreflect.MessageDescriptor) {
+	// 嵌套类型随顶层消息一起复制
+	if top := topLevelMessage(md); top != nil {
+		c.resolveMessage(top)
+		return
+	}
+
+	msgName := md.FullName()
+	if c.visit[msgName] {
+		return
+	}
+
+	c.visit[msgName] = true
+
+	c.resolveFields(md.ParentFile().Path(), md)
 
 	fd := c.getFile(md)
-	fd.MessageType = append(fd.MessageType, protodesc.ToDescriptorProto(md))
+	dp := protodesc.ToDescriptorProto(md)
+	// 消息中声明的扩展会引用被扩展的类型，路由用不到，直接去掉
+	stripExtensions(dp)
+	fd.MessageType = append(fd.MessageType, dp)
 
 	// 只有带 route 信息的方法会调用 resolveMessage，所以相关的文件都要标记为 API File
 
 	c.apiFiles[fd.GetName()] = true
 }
 
+func (c *gapiServiceResolver) resolveEnum(ed protoreflect.EnumDescriptor) {
+	if top := topLevelMessage(ed); top != nil {
+		c.resolveMessage(top)
+		return
+	}
+
+	if c.visit[ed.FullName()] {
+		return
+	}
+	c.visit[ed.FullName()] = true
+
+	fd := c.getFile(ed)
+	fd.EnumType = append(fd.EnumType, protodesc.ToEnumDescriptorProto(ed))
+	c.apiFiles[fd.GetName()] = true
+}
+
 func (c *gapiServiceResolver) resolveService(sd protoreflect.ServiceDescriptor) error {
 	// 因为涉及到对 service 和 method 的 options 检查，所以需要手动实现 protodesc.ToServiceDescriptorProto
-	if v, _ := proto.GetExtension(sd.Options(), annotation.E_Server).(string); v == "" {
+	if v, _ := proto.GetExtension(sd.Options(), annotation.E_Server).(string); v == "" || !c.opts.acceptService(sd) {
 		return nil
 	}
 
@@ -113,9 +287,14 @@ func (c *gapiServiceResolver) resolveService(sd protoreflect.ServiceDescriptor)
 		if md.IsStreamingClient() || md.IsStreamingServer() || proto.GetExtension(md.Options(), annotation.E_Http) == nil {
 			continue
 		}
+		if !c.opts.acceptMethod(md) {
+			continue
+		}
 
 		c.resolveMessage(md.Input())
 		c.resolveMessage(md.Output())
+		c.addDependency(sd.ParentFile().Path(), md.Input())
+		c.addDependency(sd.ParentFile().Path(), md.Output())
 
 		routeMethods = append(routeMethods, protodesc.ToMethodDescriptorProto(md))
 	}
@@ -124,36 +303,41 @@ func (c *gapiServiceResolver) resolveService(sd protoreflect.ServiceDescriptor)
 		return nil
 	}
 
-	fd := c.getFile(sd.ParentFile())
-	fd.Service = append(fd.Service, &descriptorpb.ServiceDescriptorProto{
+	sdp := &descriptorpb.ServiceDescriptorProto{
 		Name:    proto.String(string(sd.Name())),
 		Options: proto.Clone(sd.Options()).(*descriptorpb.ServiceOptions),
 		Method:  routeMethods,
-	})
+	}
+	for _, transform := range c.opts.serviceTransforms {
+		err := transform(sdp)
+		if err != nil {
+			return fmt.Errorf("transform service %s: %w", sd.FullName(), err)
+		}
+	}
+	fd := c.getFile(sd.ParentFile())
+	fd.Service = append(fd.Service, sdp)
 	c.apiFiles[fd.GetName()] = true
 	return nil
 }
 
-func CollectGapiFiles(srv *grpc.Server) (*descriptorpb.FileDescriptorSet, error) {
-	serviceInfo := srv.GetServiceInfo()
-	svcNames := make([]string, 0, len(serviceInfo))
-	for name := range serviceInfo {
-		svcNames = append(svcNames, name)
-	}
-	sort.Strings(svcNames)
+func CollectGapiFiles(srv ServiceInfoProvider, opts ...Option) (*descriptorpb.FileDescriptorSet, error) {
+	o := applyOptions(opts)
 
 	resolver := &gapiServiceResolver{
+		opts:     o,
 		files:    make(map[string]*descriptorpb.FileDescriptorProto),
+		origins:  make(map[string]protoreflect.FileDescriptor),
 		visit:    make(map[protoreflect.FullName]bool),
 		apiFiles: make(map[string]bool),
+		deps:     make(map[string]map[string]bool),
 	}
-	for _, name := range svcNames {
-		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	for _, name := range sortedServiceNames(srv) {
+		sd, err := findService(o.resolver, name)
 		if err != nil {
 			return nil, err
 		}
 
-		err = resolver.resolveService(d.(protoreflect.ServiceDescriptor))
+		err = resolver.resolveService(sd)
 		if err != nil {
 			return nil, err
 		}
@@ -165,6 +349,13 @@ func CollectGapiFiles(srv *grpc.Server) (*descriptorpb.FileDescriptorSet, error)
 		if !resolver.apiFiles[fd.GetName()] {
 			continue
 		}
+		fd.Dependency = resolver.dependencies(fd.GetName())
+		for _, transform := range o.fileTransforms {
+			err := transform(fd)
+			if err != nil {
+				return nil, fmt.Errorf("transform file %s: %w", fd.GetName(), err)
+			}
+		}
 		fds = append(fds, fd)
 	}
 
@@ -173,6 +364,43 @@ func CollectGapiFiles(srv *grpc.Server) (*descriptorpb.FileDescriptorSet, error)
 	}, nil
 }
 
+// dependencies 返回 file 实际引用的文件，保持原文件中 import 的顺序，通过 public import 间接引用的文件排在最后
+func (c *gapiServiceResolver) dependencies(file string) []string {
+	deps := c.deps[file]
+	if len(deps) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(deps))
+	added := make(map[string]bool, len(deps))
+	imports := c.origins[file].Imports()
+	for i := 0; i < imports.Len(); i++ {
+		path := imports.Get(i).Path()
+		if deps[path] && !added[path] {
+			added[path] = true
+			result = append(result, path)
+		}
+	}
+	if len(result) < len(deps) {
+		indirect := make([]string, 0, len(deps)-len(result))
+		for path := range deps {
+			if !added[path] {
+				indirect = append(indirect, path)
+			}
+		}
+		sort.Strings(indirect)
+		result = append(result, indirect...)
+	}
+	return result
+}
+
+func stripExtensions(dp *descriptorpb.DescriptorProto) {
+	dp.Extension = nil
+	for _, nested := range dp.NestedType {
+		stripExtensions(nested)
+	}
+}
+
+// newFileDescriptor 只复制文件的基本信息，import 在收集完成后根据实际引用的类型生成
 func newFileDescriptor(f protoreflect.FileDescriptor) *descriptorpb.FileDescriptorProto {
 	fd := &descriptorpb.FileDescriptorProto{
 		Name: proto.String(f.Path()),
@@ -180,16 +408,11 @@ func newFileDescriptor(f protoreflect.FileDescriptor) *descriptorpb.FileDescript
 	if f.Package() != "" {
 		fd.Package = proto.String(string(f.Package()))
 	}
-	imports := f.Imports()
-	for i := 0; i < imports.Len(); i++ {
-		fileImport := imports.Get(i)
-		fd.Dependency = append(fd.Dependency, fileImport.Path())
-		if fileImport.IsPublic {
-			fd.PublicDependency = append(fd.PublicDependency, int32(i))
-		}
-		if fileImport.IsWeak {
-			fd.WeakDependency = append(fd.WeakDependency, int32(i))
-		}
+	if f.Syntax() == protoreflect.Proto3 {
+		fd.Syntax = proto.String(f.Syntax().String())
+	}
+	if opts, ok := f.Options().(*descriptorpb.FileOptions); ok && opts != nil {
+		fd.Options = proto.Clone(opts).(*descriptorpb.FileOptions)
 	}
 	return fd
 }
@@ -0,0 +1,313 @@
+package reflection
+
+import (
+	"errors"
+	"testing"
+
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func field(name string, number int32, ty descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     ty.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func enumType(name string, values ...string) *descriptorpb.EnumDescriptorProto {
+	ed := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i, v := range values {
+		ed.Value = append(ed.Value, &descriptorpb.EnumValueDescriptorProto{Name: proto.String(v), Number: proto.Int32(int32(i))})
+	}
+	return ed
+}
+
+// makeTestFiles 在 dir 中构造 common.proto、unused.proto 和 api.proto，api.proto 引用了 common.proto 中的顶层枚举和嵌套消息，unused.proto 只被 import 但没有被引用
+func makeTestFiles(dir string, pkgName string) []*descriptorpb.FileDescriptorProto {
+	pkg := "." + pkgName + "."
+	common := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(dir + "/common.proto"),
+		Package: proto.String(pkgName),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			enumType("Level", "LEVEL_UNKNOWN", "LEVEL_HIGH"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Outer"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("inner", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, pkg+"Outer.Inner"),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Inner"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("level", 1, descriptorpb.FieldDescriptorProto_TYPE_ENUM, pkg+"Level"),
+						},
+					},
+				},
+			},
+			{Name: proto.String("Unreferenced")},
+		},
+	}
+	unused := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(dir + "/unused.proto"),
+		Package:     proto.String(pkgName),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Unused")}},
+	}
+
+	mapField := field("outers", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, pkg+"Req.OutersEntry")
+	mapField.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	serviceOpts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(serviceOpts, annotation.E_Server, "reflection-server")
+	methodOpts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(methodOpts, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/call"}})
+	api := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(dir + "/api.proto"),
+		Package:    proto.String(pkgName),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"gapi/annotation.proto", dir + "/unused.proto", dir + "/common.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Req"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("inner", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, pkg+"Outer.Inner"),
+					mapField,
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("OutersEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+							field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, pkg+"Outer"),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+			{
+				Name: proto.String("Resp"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("status", 1, descriptorpb.FieldDescriptorProto_TYPE_ENUM, pkg+"Resp.Status"),
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{enumType("Status", "STATUS_OK")},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name:    proto.String("ApiService"),
+				Options: serviceOpts,
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Call"),
+						InputType:  proto.String(pkg + "Req"),
+						OutputType: proto.String(pkg + "Resp"),
+						Options:    methodOpts,
+					},
+				},
+			},
+		},
+	}
+	return []*descriptorpb.FileDescriptorProto{common, unused, api}
+}
+
+func registerTestServer(t *testing.T) *grpc.Server {
+	for _, fdp := range makeTestFiles("reflection", "gapi.testdata.reflection") {
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(fdp.GetName()); err == nil {
+			continue
+		}
+		fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = protoregistry.GlobalFiles.RegisterFile(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "gapi.testdata.reflection.ApiService",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Call"}},
+	}, struct{}{})
+	return srv
+}
+
+func TestCollectGapiFiles(t *testing.T) {
+	srv := registerTestServer(t)
+	fds, err := CollectGapiFiles(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = protodesc.NewFiles(fds)
+	if err != nil {
+		t.Fatalf("NewFiles: %v", err)
+	}
+
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, fd := range fds.File {
+		files[fd.GetName()] = fd
+	}
+	if len(files) != 2 || files["reflection/common.proto"] == nil || files["reflection/api.proto"] == nil {
+		t.Fatalf("unexpected files %v", fds.File)
+	}
+
+	api := files["reflection/api.proto"]
+	if len(api.Dependency) != 1 || api.Dependency[0] != "reflection/common.proto" || api.GetSyntax() != "proto3" {
+		t.Fatalf("api.proto dependency = %v, syntax = %q", api.Dependency, api.GetSyntax())
+	}
+	if len(api.MessageType) != 2 || len(api.Service) != 1 {
+		t.Fatalf("api.proto messages = %d, services = %d", len(api.MessageType), len(api.Service))
+	}
+
+	common := files["reflection/common.proto"]
+	if len(common.Dependency) != 0 || len(common.EnumType) != 1 || len(common.MessageType) != 1 || common.MessageType[0].GetName() != "Outer" {
+		t.Fatalf("common.proto = %v", common)
+	}
+}
+
+func TestCollectWithResolver(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+		},
+	}
+	set.File = append(set.File, makeTestFiles("local", "gapi.testdata.local")...)
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const serviceName = "gapi.testdata.local.ApiService"
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName(serviceName); err == nil {
+		t.Fatal("local service should not be registered globally")
+	}
+
+	_, err = CollectGapiFiles(ServiceNames{serviceName})
+	if err == nil {
+		t.Fatal("CollectGapiFiles() without resolver should fail")
+	}
+
+	fds, err := CollectGapiFiles(ServiceNames{serviceName}, WithResolver(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fds.File) != 2 || fds.File[1].GetName() != "local/api.proto" {
+		t.Fatalf("CollectGapiFiles() = %v", fds.File)
+	}
+
+	fds, err = CollectServerFiles(ServiceNames{serviceName}, func(path string) bool { return true }, WithResolver(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fds.File) != 5 {
+		t.Fatalf("CollectServerFiles() = %d files", len(fds.File))
+	}
+
+	_, err = CollectGapiFiles(ServiceNames{"gapi.testdata.local.Req"}, WithResolver(files))
+	if err == nil {
+		t.Fatal("CollectGapiFiles() with non-service name should fail")
+	}
+}
+
+func TestCollectWithFilterAndTransform(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+		},
+	}
+	testFiles := makeTestFiles("transform", "gapi.testdata.transform")
+	// 模拟无法识别的自定义选项
+	api := testFiles[2]
+	api.Service[0].Options.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 50000, protowire.VarintType), 1))
+	set.File = append(set.File, testFiles...)
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := ServiceNames{"gapi.testdata.transform.ApiService"}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		files int
+	}{
+		{name: "none", files: 2},
+		{name: "service filter", opts: []Option{WithServiceFilter(func(sd protoreflect.ServiceDescriptor) bool { return sd.Name() != "ApiService" })}, files: 0},
+		{name: "method filter", opts: []Option{WithMethodFilter(func(md protoreflect.MethodDescriptor) bool { return md.Name() != "Call" })}, files: 0},
+		{name: "accept all", opts: []Option{
+			WithServiceFilter(func(sd protoreflect.ServiceDescriptor) bool { return true }),
+			WithMethodFilter(func(md protoreflect.MethodDescriptor) bool { return true }),
+		}, files: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fds, err := CollectGapiFiles(services, append([]Option{WithResolver(files)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fds.File) != tt.files {
+				t.Fatalf("CollectGapiFiles() = %d files, want %d", len(fds.File), tt.files)
+			}
+		})
+	}
+
+	var transformed []string
+	fds, err := CollectGapiFiles(services, WithResolver(files),
+		WithServiceTransform(RewriteServer(func(server string) string { return server + "-canary" })),
+		WithFileTransform(StripCustomOptions),
+		WithFileTransform(func(fd *descriptorpb.FileDescriptorProto) error {
+			transformed = append(transformed, fd.GetName())
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transformed) != 2 || transformed[1] != "transform/api.proto" {
+		t.Fatalf("transformed files = %v", transformed)
+	}
+	sd := fds.File[1].Service[0]
+	if server := proto.GetExtension(sd.Options, annotation.E_Server).(string); server != "reflection-server-canary" {
+		t.Fatalf("server = %q", server)
+	}
+	if len(sd.Options.ProtoReflect().GetUnknown()) != 0 {
+		t.Fatal("unknown options should be stripped")
+	}
+	if proto.GetExtension(sd.Method[0].Options, annotation.E_Http) == nil {
+		t.Fatal("gapi options should be kept")
+	}
+	origin, err := files.FindDescriptorByName("gapi.testdata.transform.ApiService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origin.Options().ProtoReflect().GetUnknown()) == 0 {
+		t.Fatal("source descriptors should not be modified")
+	}
+
+	_, err = CollectGapiFiles(services, WithResolver(files), WithFileTransform(func(fd *descriptorpb.FileDescriptorProto) error {
+		return errors.New("rejected")
+	}))
+	if err == nil {
+		t.Fatal("CollectGapiFiles() should return transform error")
+	}
+}
@@ -0,0 +1,168 @@
+package reflection
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// remoteFiles 通过 server reflection 的双向流下载文件描述符，服务端在同一个流上不会重复发送已经发送过的文件
+type remoteFiles struct {
+	stream rpb.ServerReflection_ServerReflectionInfoClient
+	files  map[string]*descriptorpb.FileDescriptorProto
+	order  []string
+}
+
+type reflectionError struct {
+	code    codes.Code
+	message string
+}
+
+func (e *reflectionError) Error() string {
+	return "server reflection: " + e.message + " (" + e.code.String() + ")"
+}
+
+func (r *remoteFiles) request(req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	err := r.stream.Send(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if errResp := resp.GetErrorResponse(); errResp != nil {
+		return nil, &reflectionError{code: codes.Code(errResp.ErrorCode), message: errResp.ErrorMessage}
+	}
+	return resp, nil
+}
+
+func (r *remoteFiles) addFiles(resp *rpb.ServerReflectionResponse) error {
+	fdResp := resp.GetFileDescriptorResponse()
+	if fdResp == nil {
+		return fmt.Errorf("server reflection: unexpected response %T", resp.MessageResponse)
+	}
+	for _, data := range fdResp.FileDescriptorProto {
+		fd := &descriptorpb.FileDescriptorProto{}
+		err := proto.Unmarshal(data, fd)
+		if err != nil {
+			return err
+		}
+		if r.files[fd.GetName()] == nil {
+			r.files[fd.GetName()] = fd
+			r.order = append(r.order, fd.GetName())
+		}
+	}
+	return nil
+}
+
+func (r *remoteFiles) listServices() ([]string, error) {
+	resp, err := r.request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	listResp := resp.GetListServicesResponse()
+	if listResp == nil {
+		return nil, fmt.Errorf("server reflection: unexpected response %T", resp.MessageResponse)
+	}
+	names := make([]string, 0, len(listResp.Service))
+	for _, svc := range listResp.Service {
+		names = append(names, svc.Name)
+	}
+	return names, nil
+}
+
+func (r *remoteFiles) fetchSymbol(symbol string) error {
+	resp, err := r.request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: symbol,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return r.addFiles(resp)
+}
+
+// fetchDependencies 补全服务端没有随文件一起发送的依赖
+func (r *remoteFiles) fetchDependencies() error {
+	for i := 0; i < len(r.order); i++ {
+		for _, dep := range r.files[r.order[i]].Dependency {
+			if r.files[dep] != nil {
+				continue
+			}
+			resp, err := r.request(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+					FileByFilename: dep,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			err = r.addFiles(resp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CollectRemoteGapiFiles 通过 conn 访问后端的 gRPC server reflection 服务（grpc.reflection.v1alpha），下载所有服务所在的文件，
+// 然后与 CollectGapiFiles 一样只保留带有 gapi 路由信息的服务和它们引用的类型。opts 中的 WithResolver 会被忽略。
+func CollectRemoteGapiFiles(ctx context.Context, conn grpc.ClientConnInterface, opts ...Option) (*descriptorpb.FileDescriptorSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote := &remoteFiles{
+		stream: stream,
+		files:  make(map[string]*descriptorpb.FileDescriptorProto),
+	}
+
+	services, err := remote.listServices()
+	if err != nil {
+		return nil, err
+	}
+	found := make([]string, 0, len(services))
+	for _, name := range services {
+		err = remote.fetchSymbol(name)
+		if err != nil {
+			// 服务端找不到描述符的服务（比如没有注册到 resolver 的服务）直接跳过
+			if re, ok := err.(*reflectionError); ok && re.code == codes.NotFound {
+				continue
+			}
+			return nil, err
+		}
+		found = append(found, name)
+	}
+	err = remote.fetchDependencies()
+	if err != nil {
+		return nil, err
+	}
+	_ = stream.CloseSend()
+
+	set := &descriptorpb.FileDescriptorSet{
+		File: make([]*descriptorpb.FileDescriptorProto, 0, len(remote.order)),
+	}
+	for _, name := range remote.order {
+		set.File = append(set.File, remote.files[name])
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return CollectGapiFiles(ServiceNames(found), append(opts, WithResolver(files))...)
+}
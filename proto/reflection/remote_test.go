@@ -0,0 +1,76 @@
+package reflection
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCollectRemoteGapiFiles(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+		},
+	}
+	set.File = append(set.File, makeTestFiles("remote", "gapi.testdata.remote")...)
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "gapi.testdata.remote.ApiService",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Call"}},
+	}, struct{}{})
+	// 反射服务自身的描述符不在 files 中，CollectRemoteGapiFiles 需要跳过它
+	rpb.RegisterServerReflectionServer(srv, reflection.NewServer(reflection.ServerOptions{
+		Services:           srv,
+		DescriptorResolver: files,
+	}))
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	fds, err := CollectRemoteGapiFiles(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fds.File) != 2 || fds.File[0].GetName() != "remote/common.proto" || fds.File[1].GetName() != "remote/api.proto" {
+		t.Fatalf("CollectRemoteGapiFiles() = %v", fds.File)
+	}
+	api := fds.File[1]
+	if len(api.Service) != 1 || api.Service[0].GetName() != "ApiService" || len(api.Dependency) != 1 {
+		t.Fatalf("api.proto = %v", api)
+	}
+	if _, err := protodesc.NewFiles(fds); err != nil {
+		t.Fatalf("NewFiles: %v", err)
+	}
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("remote/api.proto"); err == nil {
+		t.Fatal("remote files should not be registered globally")
+	}
+}
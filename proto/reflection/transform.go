@@ -0,0 +1,80 @@
+package reflection
+
+import (
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RewriteServer 返回改写服务 (gapi.server) 的 transform，比如部署时把 user-svc 改成 user-svc-canary
+func RewriteServer(rewrite func(server string) string) func(sd *descriptorpb.ServiceDescriptorProto) error {
+	return func(sd *descriptorpb.ServiceDescriptorProto) error {
+		if sd.Options == nil {
+			sd.Options = &descriptorpb.ServiceOptions{}
+		}
+		server, _ := proto.GetExtension(sd.Options, annotation.E_Server).(string)
+		proto.SetExtension(sd.Options, annotation.E_Server, rewrite(server))
+		return nil
+	}
+}
+
+// StripCustomOptions 删除文件中 gapi 注解以外的自定义选项，包括无法识别的选项
+func StripCustomOptions(fd *descriptorpb.FileDescriptorProto) error {
+	stripOptions(fd.Options)
+	for _, md := range fd.MessageType {
+		stripMessageOptions(md)
+	}
+	for _, ed := range fd.EnumType {
+		stripEnumOptions(ed)
+	}
+	for _, sd := range fd.Service {
+		stripOptions(sd.Options)
+		for _, md := range sd.Method {
+			stripOptions(md.Options)
+		}
+	}
+	return nil
+}
+
+func stripMessageOptions(md *descriptorpb.DescriptorProto) {
+	stripOptions(md.Options)
+	for _, field := range md.Field {
+		stripOptions(field.Options)
+	}
+	for _, oneof := range md.OneofDecl {
+		stripOptions(oneof.Options)
+	}
+	for _, nested := range md.NestedType {
+		stripMessageOptions(nested)
+	}
+	for _, ed := range md.EnumType {
+		stripEnumOptions(ed)
+	}
+}
+
+func stripEnumOptions(ed *descriptorpb.EnumDescriptorProto) {
+	stripOptions(ed.Options)
+	for _, v := range ed.Value {
+		stripOptions(v.Options)
+	}
+}
+
+func stripOptions(opts proto.Message) {
+	m := opts.ProtoReflect()
+	if !m.IsValid() {
+		return
+	}
+	gapiPackage := annotation.File_gapi_annotation_proto.Package()
+	var custom []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.IsExtension() && fd.ParentFile().Package() != gapiPackage {
+			custom = append(custom, fd)
+		}
+		return true
+	})
+	for _, fd := range custom {
+		m.Clear(fd)
+	}
+	m.SetUnknown(nil)
+}
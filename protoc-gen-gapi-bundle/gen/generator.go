@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"github.com/vizee/gapi-plus/proto/discovery"
+	"github.com/vizee/gapi-plus/proto/reflection"
+	gapiproto "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	protoPackage        = protogen.GoImportPath("google.golang.org/protobuf/proto")
+	descriptorpbPackage = protogen.GoImportPath("google.golang.org/protobuf/types/descriptorpb")
+)
+
+type Config struct {
+	Out          *string
+	StripOptions *bool
+}
+
+type Generator struct {
+	conf *Config
+}
+
+// bundle 对应一个 Go 包，同一个包中所有文件的服务打包在一起
+type bundle struct {
+	importPath protogen.GoImportPath
+	pkgName    protogen.GoPackageName
+	dir        string
+	sources    []string
+	services   reflection.ServiceNames
+}
+
+func (g *Generator) Run(plugin *protogen.Plugin) error {
+	files := new(protoregistry.Files)
+	for _, f := range plugin.Files {
+		err := files.RegisterFile(f.Desc)
+		if err != nil {
+			return err
+		}
+	}
+
+	var bundles []*bundle
+	byPackage := make(map[protogen.GoImportPath]*bundle)
+	for _, f := range plugin.Files {
+		if !f.Generate {
+			continue
+		}
+		b := byPackage[f.GoImportPath]
+		if b == nil {
+			b = &bundle{
+				importPath: f.GoImportPath,
+				pkgName:    f.GoPackageName,
+				dir:        path.Dir(f.GeneratedFilenamePrefix),
+			}
+			byPackage[f.GoImportPath] = b
+			bundles = append(bundles, b)
+		}
+		b.sources = append(b.sources, f.Desc.Path())
+		for _, svc := range f.Services {
+			b.services = append(b.services, string(svc.Desc.FullName()))
+		}
+	}
+
+	for _, b := range bundles {
+		opts := []reflection.Option{reflection.WithResolver(files)}
+		if g.conf.StripOptions != nil && *g.conf.StripOptions {
+			opts = append(opts, reflection.WithFileTransform(reflection.StripCustomOptions))
+		}
+		fds, err := reflection.CollectGapiFiles(b.services, opts...)
+		if err != nil {
+			return err
+		}
+		if len(fds.File) == 0 {
+			continue
+		}
+		err = g.generate(plugin, b, fds)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) generate(plugin *protogen.Plugin, b *bundle, fds *descriptorpb.FileDescriptorSet) error {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fds)
+	if err != nil {
+		return err
+	}
+	checksum, err := discovery.Checksum(fds)
+	if err != nil {
+		return err
+	}
+
+	gf := plugin.NewGeneratedFile(path.Join(b.dir, *g.conf.Out), b.importPath)
+	gf.P("// Code generated by protoc-gen-gapi-bundle. DO NOT EDIT.")
+	gf.P("// source: ", strings.Join(b.sources, ", "))
+	gf.P()
+	gf.P("package ", b.pkgName)
+	gf.P()
+	gf.P("// GapiBundleChecksum 是 GapiBundle 的 sha1 校验和，与 discovery.Checksum 的结果相同")
+	gf.P("const GapiBundleChecksum = ", strconv.Quote(checksum))
+	gf.P()
+	gf.P("// gapiBundle 是过滤后的 gapi 描述符集合，包含以下路由:")
+	gf.P("//")
+	for _, line := range routeLines(fds) {
+		gf.P("//\t", line)
+	}
+	gf.P("var gapiBundle = []byte{")
+	for len(data) > 0 {
+		n := len(data)
+		if n > 16 {
+			n = 16
+		}
+		var line strings.Builder
+		for i, c := range data[:n] {
+			if i > 0 {
+				line.WriteByte(' ')
+			}
+			fmt.Fprintf(&line, "0x%02x,", c)
+		}
+		gf.P(line.String())
+		data = data[n:]
+	}
+	gf.P("}")
+	gf.P()
+	gf.P("// GapiBundle 返回嵌入的 gapi 描述符集合，每次调用都返回新的副本")
+	gf.P("func GapiBundle() *", descriptorpbPackage.Ident("FileDescriptorSet"), " {")
+	gf.P("fds := &", descriptorpbPackage.Ident("FileDescriptorSet"), "{}")
+	gf.P("err := ", protoPackage.Ident("Unmarshal"), "(gapiBundle, fds)")
+	gf.P("if err != nil {")
+	gf.P("panic(err)")
+	gf.P("}")
+	gf.P("return fds")
+	gf.P("}")
+	return nil
+}
+
+// routeLines 列出集合中的路由，方便在代码评审中确认注册的内容
+func routeLines(fds *descriptorpb.FileDescriptorSet) []string {
+	var lines []string
+	for _, fd := range fds.File {
+		for _, sd := range fd.Service {
+			server, _ := proto.GetExtension(sd.Options, gapiproto.E_Server).(string)
+			prefix, _ := proto.GetExtension(sd.Options, gapiproto.E_PathPrefix).(string)
+			for _, md := range sd.Method {
+				httpOpt, _ := proto.GetExtension(md.Options, gapiproto.E_Http).(*gapiproto.Http)
+				method, path := descriptor.HttpRule(httpOpt)
+				fullMethod := "/" + fd.GetPackage() + "." + sd.GetName() + "/" + md.GetName()
+				if fd.GetPackage() == "" {
+					fullMethod = "/" + sd.GetName() + "/" + md.GetName()
+				}
+				lines = append(lines, server+" "+method+" "+prefix+path+" "+fullMethod)
+			}
+		}
+	}
+	return lines
+}
+
+func NewGenerator(conf *Config) *Generator {
+	return &Generator{
+		conf: conf,
+	}
+}
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/discovery"
+	"github.com/vizee/gapi-plus/proto/plugintest"
+	"github.com/vizee/gapi-plus/proto/reflection"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func makeRequest(t *testing.T) *pluginpb.CodeGeneratorRequest {
+	req := plugintest.Request(t, "../../testdata/pdtest/pdtest.pd", "pdtest.proto")
+	req.Parameter = proto.String("paths=source_relative")
+	// 没有 gapi.server 的服务不会打包
+	fd := plugintest.File(t, req, "pdtest.proto")
+	fd.Service = append(fd.Service, &descriptorpb.ServiceDescriptorProto{
+		Name: proto.String("InternalService"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			{
+				Name:       proto.String("Call"),
+				InputType:  proto.String(".gapi.testdata.pdtest.User"),
+				OutputType: proto.String(".gapi.testdata.pdtest.User"),
+			},
+		},
+	})
+	return req
+}
+
+func TestGenerator(t *testing.T) {
+	var plugin *protogen.Plugin
+	out := "gapi_bundle.pb.go"
+	strip := true
+	resp, err := plugintest.Run(t, makeRequest(t), func(p *protogen.Plugin) error {
+		plugin = p
+		return NewGenerator(&Config{Out: &out, StripOptions: &strip}).Run(p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	if len(resp.File) != 1 || resp.File[0].GetName() != "gapi_bundle.pb.go" {
+		t.Fatalf("generated files = %v", resp.File)
+	}
+
+	content := resp.File[0].GetContent()
+	f, err := parser.ParseFile(token.NewFileSet(), resp.File[0].GetName(), content, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, content)
+	}
+	if f.Name.Name != "pdtest" {
+		t.Errorf("package = %s", f.Name.Name)
+	}
+	checksum, data := parseBundle(t, f)
+
+	var got descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := new(protoregistry.Files)
+	for _, pf := range plugin.Files {
+		_ = files.RegisterFile(pf.Desc)
+	}
+	want, err := reflection.CollectGapiFiles(reflection.ServiceNames{"gapi.testdata.pdtest.TestService"},
+		reflection.WithResolver(files), reflection.WithFileTransform(reflection.StripCustomOptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&got, want) {
+		t.Errorf("gapiBundle = %v, want %v", &got, want)
+	}
+	wantChecksum, err := discovery.Checksum(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != wantChecksum {
+		t.Errorf("GapiBundleChecksum = %s, want %s", checksum, wantChecksum)
+	}
+
+	for _, want := range []string{
+		"test-server POST /path/prefix/add /gapi.testdata.pdtest.TestService/Add",
+		"test-server POST /path/prefix/say /gapi.testdata.pdtest.TestService/Say",
+		"func GapiBundle() *descriptorpb.FileDescriptorSet {",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated content missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "InternalService") {
+		t.Error("service without gapi.server should not be bundled")
+	}
+}
+
+// parseBundle 从生成的代码中取出 GapiBundleChecksum 和 gapiBundle 的值
+func parseBundle(t *testing.T, f *ast.File) (string, []byte) {
+	var (
+		checksum string
+		data     []byte
+		found    int
+	)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok == token.IMPORT {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			switch vs.Names[0].Name {
+			case "GapiBundleChecksum":
+				var err error
+				checksum, err = strconv.Unquote(vs.Values[0].(*ast.BasicLit).Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				found++
+			case "gapiBundle":
+				for _, elt := range vs.Values[0].(*ast.CompositeLit).Elts {
+					c, err := strconv.ParseUint(elt.(*ast.BasicLit).Value, 0, 8)
+					if err != nil {
+						t.Fatal(err)
+					}
+					data = append(data, byte(c))
+				}
+				found++
+			}
+		}
+	}
+	if found != 2 {
+		t.Fatal("missing GapiBundleChecksum or gapiBundle")
+	}
+	return checksum, data
+}
@@ -0,0 +1,18 @@
+module github.com/vizee/gapi-plus/protoc-gen-gapi-bundle
+
+go 1.20
+
+require (
+	github.com/vizee/gapi-plus/proto v0.3.0
+	github.com/vizee/gapi-proto-go v0.0.0-20230505112701-bca324ad1c1e
+	google.golang.org/protobuf v1.30.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
+	google.golang.org/grpc v1.55.0 // indirect
+)
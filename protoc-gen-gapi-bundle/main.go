@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/vizee/gapi-plus/protoc-gen-gapi-bundle/gen"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	var flags flag.FlagSet
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(gen.NewGenerator(&gen.Config{
+		Out:          flags.String("out", "gapi_bundle.pb.go", "output file name in the package directory"),
+		StripOptions: flags.Bool("strip_options", true, "strip non-gapi custom options"),
+	}).Run)
+}
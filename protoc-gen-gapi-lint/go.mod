@@ -0,0 +1,30 @@
+module github.com/vizee/gapi-plus/protoc-gen-gapi-lint
+
+go 1.20
+
+require (
+	github.com/vizee/gapi-plus/apimeta v0.3.0
+	github.com/vizee/gapi-plus/proto v0.3.0
+	github.com/vizee/gapi-plus/protoc-gen-gapi-swagger v0.3.0
+	github.com/vizee/gapi-proto-go v0.0.0-20230505112701-bca324ad1c1e
+	google.golang.org/protobuf v1.30.0
+)
+
+require (
+	github.com/go-openapi/jsonpointer v0.19.5 // indirect
+	github.com/go-openapi/jsonreference v0.20.0 // indirect
+	github.com/go-openapi/spec v0.20.9 // indirect
+	github.com/go-openapi/swag v0.19.15 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.7.6 // indirect
+	github.com/vizee/gapi v0.4.0 // indirect
+	github.com/vizee/gapi-plus/httpcache v0.3.0 // indirect
+	github.com/vizee/jsonpb v0.2.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
+	google.golang.org/grpc v1.55.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
@@ -0,0 +1,237 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	"github.com/vizee/gapi-plus/apimeta/check"
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"github.com/vizee/gapi-plus/protoc-gen-gapi-swagger/annotations"
+	gapiproto "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// knownAnnotations 是 apimeta 和 protoc-gen-gapi-swagger 能识别的注解，带 '.' 的注解属于 handler 或中间件，不做检查
+var knownAnnotations = []string{
+	"summary", "description", "tags", "deprecated", "sunset",
+	"handler", "cache", "retry", "host", "attr", "fieldmask",
+	"accept", "produce", "param", "header", "success", "failure", "response", "security",
+}
+
+var paramLocations = map[string]bool{
+	"query":    true,
+	"path":     true,
+	"header":   true,
+	"body":     true,
+	"formData": true,
+}
+
+type Config struct {
+	// Annotations 是额外允许的注解名，用逗号分隔
+	Annotations *string
+	// Warnings 不为 nil 时输出警告
+	Warnings io.Writer
+}
+
+type Linter struct {
+	conf  *Config
+	known map[string]bool
+	diags []apimeta.Diagnostic
+}
+
+func (l *Linter) Diagnostics() []apimeta.Diagnostic {
+	return l.diags
+}
+
+func (l *Linter) report(severity apimeta.Severity, d protoreflect.Descriptor, format string, args ...any) {
+	diag := apimeta.Diagnostic{
+		Severity: severity,
+		Source:   apimeta.SourceLocation{File: d.ParentFile().Path()},
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if loc := d.ParentFile().SourceLocations().ByDescriptor(d); len(loc.Path) > 0 {
+		diag.Source.Line = loc.StartLine + 1
+		diag.Source.Column = loc.StartColumn + 1
+	}
+	if md, ok := d.(protoreflect.MethodDescriptor); ok {
+		diag.Method = "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	}
+	l.diags = append(l.diags, diag)
+}
+
+func (l *Linter) lintAnnotations(d protoreflect.Descriptor, ans annotations.Annotations) {
+	for _, an := range ans {
+		if !l.known[an.Name()] && !strings.Contains(an.Name(), ".") {
+			l.report(apimeta.SeverityWarning, d, "unknown annotation '@%s'", an.Name())
+		}
+	}
+
+	params := ans.Get("param")
+	for i := 0; i < params.LineNum(); i++ {
+		fields := annotations.ParseLineFields(params.Line(i), ' ')
+		if len(fields) < 3 {
+			l.report(apimeta.SeverityError, d, "malformed @param '%s': want <name> <in> <type> [required] [comment]", params.Line(i))
+		} else if in := annotations.GetFieldValue(fields, 1); !paramLocations[in] {
+			l.report(apimeta.SeverityError, d, "malformed @param '%s': unknown location '%s'", params.Line(i), in)
+		}
+	}
+
+	for _, name := range []string{"success", "failure", "response"} {
+		an := ans.Get(name)
+		for i := 0; i < an.LineNum(); i++ {
+			err := checkResponseLine(an.Line(i))
+			if err != nil {
+				l.report(apimeta.SeverityError, d, "malformed @%s '%s': %v", name, an.Line(i), err)
+			}
+		}
+	}
+}
+
+func checkResponseLine(line string) error {
+	fields := annotations.ParseLineFields(line, ' ')
+	if len(fields) < 3 {
+		return errors.New("want <codes> {<type>} <data> [comment]")
+	}
+	for _, code := range annotations.ParseLineFields(annotations.GetFieldValue(fields, 0), ',') {
+		if code == "default" || code == "all" {
+			continue
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 100 || n > 599 {
+			return fmt.Errorf("invalid status code '%s'", code)
+		}
+	}
+	// 与 protoc-gen-gapi-swagger 一致，类型两边的花括号可以省略
+	ty := annotations.GetFieldValue(fields, 1)
+	if strings.TrimPrefix(strings.TrimSuffix(ty, "}"), "{") == "" {
+		return fmt.Errorf("invalid type '%s'", ty)
+	}
+	return nil
+}
+
+// pathParams 返回路径中的参数名，包括 `:name` 和 `*name`
+func pathParams(path string) map[string]bool {
+	params := make(map[string]bool)
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			params[seg[1:]] = true
+		}
+	}
+	return params
+}
+
+func (l *Linter) lintService(svc *protogen.Service) {
+	opts := svc.Desc.Options()
+	server, _ := proto.GetExtension(opts, gapiproto.E_Server).(string)
+	if server == "" {
+		return
+	}
+	pathPrefix, _ := proto.GetExtension(opts, gapiproto.E_PathPrefix).(string)
+
+	serviceAns := annotations.ExtractAnnotations(string(svc.Comments.Leading))
+	l.lintAnnotations(svc.Desc, serviceAns)
+
+	for _, method := range svc.Methods {
+		httpOpt, _ := proto.GetExtension(method.Desc.Options(), gapiproto.E_Http).(*gapiproto.Http)
+		if httpOpt == nil {
+			continue
+		}
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			l.report(apimeta.SeverityError, method.Desc, "streaming method '%s' cannot have gapi.http", method.Desc.Name())
+			continue
+		}
+
+		methodAns := annotations.ExtractAnnotations(string(method.Comments.Leading))
+		l.lintAnnotations(method.Desc, methodAns)
+
+		_, path := descriptor.HttpRule(httpOpt)
+		params := pathParams(pathPrefix + path)
+		for _, field := range method.Input.Fields {
+			bind, _ := proto.GetExtension(field.Desc.Options(), gapiproto.E_Bind).(gapiproto.FIELD_BIND)
+			if bind != gapiproto.FIELD_BIND_FROM_PARAMS {
+				continue
+			}
+			name, _ := proto.GetExtension(field.Desc.Options(), gapiproto.E_Alias).(string)
+			if name == "" {
+				name = string(field.Desc.Name())
+			}
+			if !params[name] {
+				l.report(apimeta.SeverityError, method.Desc, "field '%s' of %s binds FROM_PARAMS but path '%s' has no parameter '%s'", field.Desc.Name(), method.Input.Desc.FullName(), pathPrefix+path, name)
+			}
+		}
+	}
+}
+
+// lintRoutes 使用和网关自检相同的方式解析生成文件中的服务，报告解析错误和路由冲突
+func (l *Linter) lintRoutes(plugin *protogen.Plugin) {
+	files := make([]*descriptorpb.FileDescriptorProto, 0, len(plugin.Files))
+	generate := make(map[string]bool)
+	for _, f := range plugin.Files {
+		files = append(files, f.Proto)
+		generate[f.Proto.GetName()] = f.Generate
+	}
+	report := (&check.Checker{}).CheckFiles(files, func(fd *descriptorpb.FileDescriptorProto) bool {
+		return generate[fd.GetName()]
+	})
+	l.diags = append(l.diags, report.Diagnostics...)
+}
+
+func (l *Linter) Run(plugin *protogen.Plugin) error {
+	for _, f := range plugin.Files {
+		if !f.Generate {
+			continue
+		}
+		for _, svc := range f.Services {
+			l.lintService(svc)
+		}
+	}
+	l.lintRoutes(plugin)
+
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		a, b := &l.diags[i].Source, &l.diags[j].Source
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	var errs []string
+	for i := range l.diags {
+		diag := &l.diags[i]
+		if diag.Severity == apimeta.SeverityError {
+			errs = append(errs, diag.Error())
+		} else if l.conf.Warnings != nil {
+			fmt.Fprintf(l.conf.Warnings, "%s: warning: %s\n", diag.Source.String(), diag.Message)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func NewLinter(conf *Config) *Linter {
+	known := make(map[string]bool, len(knownAnnotations))
+	for _, name := range knownAnnotations {
+		known[name] = true
+	}
+	if conf.Annotations != nil {
+		for _, name := range strings.Split(*conf.Annotations, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				known[strings.ToLower(name)] = true
+			}
+		}
+	}
+	return &Linter{
+		conf:  conf,
+		known: known,
+	}
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"github.com/vizee/gapi-plus/proto/plugintest"
+	gapiproto "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func httpMethod(name string, rule *gapiproto.Http, streaming bool) *descriptorpb.MethodDescriptorProto {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, gapiproto.E_Http, rule)
+	return &descriptorpb.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(".gapi.testdata.pdtest.GetUserRequest"),
+		OutputType:      proto.String(".gapi.testdata.pdtest.User"),
+		Options:         opts,
+		ServerStreaming: proto.Bool(streaming),
+	}
+}
+
+// makeRequest 在 testdata/pdtest 的 TestService 中加入有问题的方法，服务不再有默认的 handler
+func makeRequest(t *testing.T) *pluginpb.CodeGeneratorRequest {
+	req := plugintest.Request(t, "../../testdata/pdtest/pdtest.pd", "pdtest.proto")
+	fd := plugintest.File(t, req, "pdtest.proto")
+	idOpts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(idOpts, gapiproto.E_Bind, gapiproto.FIELD_BIND_FROM_PARAMS)
+	fd.MessageType = append(fd.MessageType, &descriptorpb.DescriptorProto{
+		Name: proto.String("GetUserRequest"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{Name: proto.String("id"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: idOpts},
+		},
+	})
+
+	sd := fd.Service[0]
+	proto.ClearExtension(sd.Options, gapiproto.E_DefaultHandler)
+	addMethod := func(md *descriptorpb.MethodDescriptorProto, line int32, comments string) {
+		if comments != "" {
+			fd.SourceCodeInfo.Location = append(fd.SourceCodeInfo.Location, &descriptorpb.SourceCodeInfo_Location{
+				Path:            descriptor.MethodPath(0, int32(len(sd.Method))),
+				Span:            []int32{line, 0, 1},
+				LeadingComments: proto.String(comments),
+			})
+		}
+		sd.Method = append(sd.Method, md)
+	}
+	addMethod(httpMethod("GetUser", &gapiproto.Http{Pattern: &gapiproto.Http_Get{Get: "/users/:id"}, Handler: "jsonapi"}, false), 200, " @success 200 {object} User\n")
+	addMethod(httpMethod("FindUser", &gapiproto.Http{Pattern: &gapiproto.Http_Get{Get: "/users/:key"}}, false), 202, " @handler application/json application/json jsonapi\n")
+	addMethod(httpMethod("ListUsers", &gapiproto.Http{Pattern: &gapiproto.Http_Get{Get: "/users"}}, false), 204, " @handler jsonapi\n @param page query\n @param id cookie string\n @failure 99 {object} Error\n @jsonapi.out data\n")
+	addMethod(httpMethod("WatchUsers", &gapiproto.Http{Pattern: &gapiproto.Http_Get{Get: "/users/watch"}, Handler: "jsonapi"}, true), 0, "")
+	addMethod(httpMethod("DeleteUser", &gapiproto.Http{Pattern: &gapiproto.Http_Delete{Delete: "/users/:id"}, Handler: "jsonapi", Use: []string{"bad name"}}, false), 0, "")
+	addMethod(httpMethod("UpdateUser", &gapiproto.Http{Pattern: &gapiproto.Http_Put{Put: "/users/:id"}, Handler: "jsonapi"}, false), 206, " @cache immutable\n")
+	return req
+}
+
+func runLinter(t *testing.T, extra string, warnings io.Writer) error {
+	_, err := plugintest.Run(t, makeRequest(t), func(plugin *protogen.Plugin) error {
+		return NewLinter(&Config{Annotations: &extra, Warnings: warnings}).Run(plugin)
+	})
+	return err
+}
+
+func TestLinter(t *testing.T) {
+	var warnings bytes.Buffer
+	err := runLinter(t, "", &warnings)
+	if err == nil {
+		t.Fatal("Run() should fail")
+	}
+
+	for _, want := range []string{
+		"pdtest.proto:203:1: field 'id' of gapi.testdata.pdtest.GetUserRequest binds FROM_PARAMS but path '/path/prefix/users/:key' has no parameter 'id'",
+		"pdtest.proto:205:1: malformed @param 'page query': want <name> <in> <type> [required] [comment]",
+		"pdtest.proto:205:1: malformed @param 'id cookie string': unknown location 'cookie'",
+		"pdtest.proto:205:1: malformed @failure '99 {object} Error': invalid status code '99'",
+		"pdtest.proto:205:1: field 'id' of gapi.testdata.pdtest.GetUserRequest binds FROM_PARAMS but path '/path/prefix/users' has no parameter 'id'",
+		"streaming method 'WatchUsers' cannot have gapi.http",
+		"pdtest.proto:205:1: invalid handler binding 'jsonapi'",
+		"pdtest.proto: invalid middleware name 'bad name'",
+		"pdtest.proto:207:1: invalid cache directive 'immutable'",
+		"route GET /path/prefix/users/:key conflicts with /path/prefix/users/:id",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Run() error missing %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "invalid method 'FindUser'") {
+		t.Errorf("handler from annotation should be accepted:\n%v", err)
+	}
+	// pdtest.proto 中 page 字段的 query 绑定无法通过 metadata.Call 传递
+	const pageWarning = "pdtest.proto:48:5: warning: query bindings of message fields 'page' are only available in Route.Bindings, metadata.Call drops them\n"
+	if warnings.String() != "pdtest.proto:16:1: warning: unknown annotation '@tag'\n"+pageWarning {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+
+	warnings.Reset()
+	_ = runLinter(t, "tag", &warnings)
+	if warnings.String() != pageWarning {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+}
+
+func TestCheckResponseLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr bool
+	}{
+		{line: `200 {object} Item "ok"`},
+		{line: "200,default {object} Item"},
+		{line: "all {string} string"},
+		{line: "200 {object}", wantErr: true},
+		{line: "ok {object} Item", wantErr: true},
+		{line: "600 {object} Item", wantErr: true},
+		{line: "200 object Item"},
+		{line: "200 {} Item", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := checkResponseLine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkResponseLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/vizee/gapi-plus/protoc-gen-gapi-lint/lint"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	var flags flag.FlagSet
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(lint.NewLinter(&lint.Config{
+		Annotations: flags.String("annotations", "", "extra allowed annotation names, comma separated"),
+		Warnings:    os.Stderr,
+	}).Run)
+}
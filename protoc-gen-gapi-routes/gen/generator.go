@@ -0,0 +1,255 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vizee/gapi-plus/apimeta"
+	"github.com/vizee/gapi-plus/apimeta/protodesc"
+	"github.com/vizee/gapi/metadata"
+	"github.com/vizee/jsonpb"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const (
+	jsonpbPackage   = protogen.GoImportPath("github.com/vizee/jsonpb")
+	metadataPackage = protogen.GoImportPath("github.com/vizee/gapi/metadata")
+	timePackage     = protogen.GoImportPath("time")
+)
+
+var kindNames = [...]string{
+	jsonpb.DoubleKind:   "DoubleKind",
+	jsonpb.FloatKind:    "FloatKind",
+	jsonpb.Int32Kind:    "Int32Kind",
+	jsonpb.Int64Kind:    "Int64Kind",
+	jsonpb.Uint32Kind:   "Uint32Kind",
+	jsonpb.Uint64Kind:   "Uint64Kind",
+	jsonpb.Sint32Kind:   "Sint32Kind",
+	jsonpb.Sint64Kind:   "Sint64Kind",
+	jsonpb.Fixed32Kind:  "Fixed32Kind",
+	jsonpb.Fixed64Kind:  "Fixed64Kind",
+	jsonpb.Sfixed32Kind: "Sfixed32Kind",
+	jsonpb.Sfixed64Kind: "Sfixed64Kind",
+	jsonpb.BoolKind:     "BoolKind",
+	jsonpb.StringKind:   "StringKind",
+	jsonpb.BytesKind:    "BytesKind",
+	jsonpb.MapKind:      "MapKind",
+	jsonpb.MessageKind:  "MessageKind",
+}
+
+var omitNames = [...]string{
+	jsonpb.OmitProtoEmpty: "OmitProtoEmpty",
+	jsonpb.OmitEmpty:      "OmitEmpty",
+	jsonpb.OmitAlways:     "OmitAlways",
+}
+
+var bindNames = [...]string{
+	metadata.BindDefault: "BindDefault",
+	metadata.BindQuery:   "BindQuery",
+	metadata.BindParams:  "BindParams",
+	metadata.BindHeader:  "BindHeader",
+	metadata.BindContext: "BindContext",
+}
+
+type Config struct {
+	Out          *string
+	IgnoreErrors *bool
+	StrictFields *bool
+	// Warnings 不为 nil 时输出生成文件中的警告，比如 metadata.Call 无法携带的绑定
+	Warnings io.Writer
+}
+
+type Generator struct {
+	conf *Config
+}
+
+// routeTable 对应一个 Go 包，同一个包中所有文件的路由生成在一起
+type routeTable struct {
+	importPath protogen.GoImportPath
+	pkgName    protogen.GoPackageName
+	dir        string
+	sources    []string
+	routes     []*metadata.Route
+}
+
+func (g *Generator) Run(plugin *protogen.Plugin) error {
+	ignoreErrors := g.conf.IgnoreErrors != nil && *g.conf.IgnoreErrors
+	p := protodesc.NewParser()
+	p.StrictFields = g.conf.StrictFields != nil && *g.conf.StrictFields
+
+	var tables []*routeTable
+	byPackage := make(map[protogen.GoImportPath]*routeTable)
+	generated := make(map[string]bool)
+	for _, f := range plugin.Files {
+		// 依赖文件只需要其中的消息，服务上的错误不影响生成
+		routes, err := p.AddFile(nil, f.Proto, ignoreErrors || !f.Generate)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Desc.Path(), err)
+		}
+		if !f.Generate {
+			continue
+		}
+		t := byPackage[f.GoImportPath]
+		if t == nil {
+			t = &routeTable{
+				importPath: f.GoImportPath,
+				pkgName:    f.GoPackageName,
+				dir:        path.Dir(f.GeneratedFilenamePrefix),
+			}
+			byPackage[f.GoImportPath] = t
+			tables = append(tables, t)
+		}
+		t.sources = append(t.sources, f.Desc.Path())
+		t.routes = append(t.routes, routes...)
+		generated[f.Desc.Path()] = true
+	}
+
+	if g.conf.Warnings != nil {
+		for _, diag := range p.Diagnostics() {
+			if diag.Severity == apimeta.SeverityWarning && generated[diag.Source.File] {
+				fmt.Fprintf(g.conf.Warnings, "%s: warning: %s\n", diag.Source.String(), diag.Message)
+			}
+		}
+	}
+
+	for _, t := range tables {
+		if len(t.routes) == 0 {
+			continue
+		}
+		g.generate(plugin, t)
+	}
+	return nil
+}
+
+// collectMessages 按照路由中出现的顺序收集所有引用到的消息
+func collectMessages(routes []*metadata.Route) ([]*jsonpb.Message, map[*jsonpb.Message]string) {
+	var msgs []*jsonpb.Message
+	names := make(map[*jsonpb.Message]string)
+	var walk func(m *jsonpb.Message)
+	walk = func(m *jsonpb.Message) {
+		if m == nil || names[m] != "" {
+			return
+		}
+		names[m] = "m" + strconv.Itoa(len(msgs))
+		msgs = append(msgs, m)
+		for i := range m.Fields {
+			walk(m.Fields[i].Ref)
+		}
+	}
+	for _, r := range routes {
+		walk(r.Call.In)
+		walk(r.Call.Out)
+	}
+	return msgs, names
+}
+
+func kindExpr(gf *protogen.GeneratedFile, kind jsonpb.Kind) string {
+	if int(kind) < len(kindNames) && kindNames[kind] != "" {
+		return gf.QualifiedGoIdent(jsonpbPackage.Ident(kindNames[kind]))
+	}
+	return gf.QualifiedGoIdent(jsonpbPackage.Ident("Kind")) + "(" + strconv.Itoa(int(kind)) + ")"
+}
+
+func bindExpr(gf *protogen.GeneratedFile, bind metadata.BindSource) string {
+	if int(bind) < len(bindNames) {
+		return gf.QualifiedGoIdent(metadataPackage.Ident(bindNames[bind]))
+	}
+	return gf.QualifiedGoIdent(metadataPackage.Ident("BindSource")) + "(" + strconv.Itoa(int(bind)) + ")"
+}
+
+func durationExpr(gf *protogen.GeneratedFile, d time.Duration) string {
+	if d%time.Millisecond == 0 {
+		return strconv.FormatInt(int64(d/time.Millisecond), 10) + " * " + gf.QualifiedGoIdent(timePackage.Ident("Millisecond"))
+	}
+	return gf.QualifiedGoIdent(timePackage.Ident("Duration")) + "(" + strconv.FormatInt(int64(d), 10) + ")"
+}
+
+func quoteStrings(ss []string) string {
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, strconv.Quote(s))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (g *Generator) generate(plugin *protogen.Plugin, t *routeTable) {
+	gf := plugin.NewGeneratedFile(path.Join(t.dir, *g.conf.Out), t.importPath)
+	gf.P("// Code generated by protoc-gen-gapi-routes. DO NOT EDIT.")
+	gf.P("// source: ", strings.Join(t.sources, ", "))
+	gf.P()
+	gf.P("package ", t.pkgName)
+	gf.P()
+
+	msgs, names := collectMessages(t.routes)
+	gf.P("// GapiRoutes 返回生成时解析出的路由，每次调用都会构造新的路由表")
+	gf.P("func GapiRoutes() []*", metadataPackage.Ident("Route"), " {")
+	for _, m := range msgs {
+		gf.P(names[m], " := &", jsonpbPackage.Ident("Message"), "{Name: ", strconv.Quote(m.Name), "}")
+	}
+	for _, m := range msgs {
+		if len(m.Fields) > 0 {
+			gf.P(names[m], ".Fields = []", jsonpbPackage.Ident("Field"), "{")
+			for i := range m.Fields {
+				f := &m.Fields[i]
+				args := []any{"{Name: ", strconv.Quote(f.Name), ", Kind: ", kindExpr(gf, f.Kind)}
+				if f.Ref != nil {
+					args = append(args, ", Ref: ", names[f.Ref])
+				}
+				args = append(args, ", Tag: ", f.Tag)
+				if f.Repeated {
+					args = append(args, ", Repeated: true")
+				}
+				if f.Omit != jsonpb.OmitProtoEmpty {
+					args = append(args, ", Omit: ", jsonpbPackage.Ident(omitNames[f.Omit]))
+				}
+				args = append(args, "},")
+				gf.P(args...)
+			}
+			gf.P("}")
+		}
+		gf.P(names[m], ".BakeTagIndex()")
+		gf.P(names[m], ".BakeNameIndex()")
+	}
+	gf.P()
+
+	gf.P("return []*", metadataPackage.Ident("Route"), "{")
+	for _, r := range t.routes {
+		c := r.Call
+		gf.P("{")
+		gf.P("Method: ", strconv.Quote(r.Method), ",")
+		gf.P("Path: ", strconv.Quote(r.Path), ",")
+		if len(r.Use) > 0 {
+			gf.P("Use: []string{", quoteStrings(r.Use), "},")
+		}
+		gf.P("Call: &", metadataPackage.Ident("Call"), "{")
+		gf.P("Server: ", strconv.Quote(c.Server), ",")
+		gf.P("Handler: ", strconv.Quote(c.Handler), ",")
+		gf.P("Method: ", strconv.Quote(c.Method), ",")
+		gf.P("In: ", names[c.In], ",")
+		gf.P("Out: ", names[c.Out], ",")
+		if len(c.Bindings) > 0 {
+			gf.P("Bindings: []", metadataPackage.Ident("FieldBinding"), "{")
+			for _, b := range c.Bindings {
+				gf.P("{Name: ", strconv.Quote(b.Name), ", Kind: ", kindExpr(gf, b.Kind), ", Tag: ", b.Tag, ", Bind: ", bindExpr(gf, b.Bind), "},")
+			}
+			gf.P("},")
+		}
+		if c.Timeout > 0 {
+			gf.P("Timeout: ", durationExpr(gf, c.Timeout), ",")
+		}
+		gf.P("},")
+		gf.P("},")
+	}
+	gf.P("}")
+	gf.P("}")
+}
+
+func NewGenerator(conf *Config) *Generator {
+	return &Generator{
+		conf: conf,
+	}
+}
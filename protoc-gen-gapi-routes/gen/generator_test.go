@@ -0,0 +1,85 @@
+package gen
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/plugintest"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update internal/pdtest/gapi_routes.pb.go")
+
+// makeRequest 使用 testdata/pdtest 生成 internal/pdtest 包，包中的测试会编译生成的代码并与 protodesc 的解析结果比较
+func makeRequest(t *testing.T, withInvalid bool) *pluginpb.CodeGeneratorRequest {
+	req := plugintest.Request(t, "../../testdata/pdtest/pdtest.pd", "pdtest.proto")
+	req.Parameter = proto.String("paths=source_relative,Mpdtest.proto=github.com/vizee/gapi-plus/protoc-gen-gapi-routes/internal/pdtest;pdtest")
+	if withInvalid {
+		fd := plugintest.File(t, req, "pdtest.proto")
+		fd.Service = append(fd.Service, &descriptorpb.ServiceDescriptorProto{Name: proto.String("PlainService")})
+	}
+	return req
+}
+
+func runGenerator(t *testing.T, req *pluginpb.CodeGeneratorRequest, ignoreErrors bool, warnings io.Writer) (*pluginpb.CodeGeneratorResponse, error) {
+	out := "gapi_routes.pb.go"
+	strict := false
+	return plugintest.Run(t, req, func(plugin *protogen.Plugin) error {
+		return NewGenerator(&Config{Out: &out, IgnoreErrors: &ignoreErrors, StrictFields: &strict, Warnings: warnings}).Run(plugin)
+	})
+}
+
+func TestGenerator(t *testing.T) {
+	var warnings bytes.Buffer
+	resp, err := runGenerator(t, makeRequest(t, false), false, &warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.File) != 1 || resp.File[0].GetName() != "gapi_routes.pb.go" {
+		t.Fatalf("generated files = %v", resp.File)
+	}
+
+	const golden = "../internal/pdtest/gapi_routes.pb.go"
+	content := resp.File[0].GetContent()
+	if *update {
+		err = os.WriteFile(golden, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != string(want) {
+		t.Errorf("%s is out of date, run go test -run TestGenerator -update:\n%s", golden, content)
+	}
+	if strings.Contains(content, `"page"`) {
+		t.Errorf("nested binding should not be generated:\n%s", content)
+	}
+	if !strings.Contains(warnings.String(), "pdtest.proto") || !strings.Contains(warnings.String(), "warning: query bindings of message fields 'page'") {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	_, err := runGenerator(t, makeRequest(t, true), false, nil)
+	if err == nil || !strings.Contains(err.Error(), "pdtest.proto: invalid service name 'PlainService'") {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	resp, err := runGenerator(t, makeRequest(t, true), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.File) != 1 || !strings.Contains(resp.File[0].GetContent(), "/gapi.testdata.pdtest.TestService/Add") {
+		t.Fatalf("generated files = %v", resp.File)
+	}
+}
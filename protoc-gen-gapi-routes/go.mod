@@ -0,0 +1,18 @@
+module github.com/vizee/gapi-plus/protoc-gen-gapi-routes
+
+go 1.20
+
+require (
+	github.com/vizee/gapi v0.4.0
+	github.com/vizee/gapi-plus/apimeta v0.3.0
+	github.com/vizee/gapi-plus/proto v0.3.0
+	github.com/vizee/jsonpb v0.2.0
+	google.golang.org/protobuf v1.30.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/vizee/gapi-plus/httpcache v0.3.0 // indirect
+	github.com/vizee/gapi-proto-go v0.0.0-20230505112701-bca324ad1c1e // indirect
+	google.golang.org/grpc v1.55.0 // indirect
+)
@@ -0,0 +1,103 @@
+// Code generated by protoc-gen-gapi-routes. DO NOT EDIT.
+// source: pdtest.proto
+
+package pdtest
+
+import (
+	metadata "github.com/vizee/gapi/metadata"
+	jsonpb "github.com/vizee/jsonpb"
+	time "time"
+)
+
+// GapiRoutes 返回生成时解析出的路由，每次调用都会构造新的路由表
+func GapiRoutes() []*metadata.Route {
+	m0 := &jsonpb.Message{Name: "gapi.testdata.pdtest.AddRequest"}
+	m1 := &jsonpb.Message{Name: "gapi.testdata.pdtest.AddResponse"}
+	m2 := &jsonpb.Message{Name: "gapi.testdata.pdtest.SayRequest"}
+	m3 := &jsonpb.Message{Name: "gapi.testdata.pdtest.SayResponse"}
+	m4 := &jsonpb.Message{Name: "gapi.testdata.pdtest.User"}
+	m5 := &jsonpb.Message{Name: "gapi.testdata.pdtest.SayResponse.Embedded"}
+	m6 := &jsonpb.Message{Name: "gapi.testdata.pdtest.SayResponse.UsersEntry"}
+	m7 := &jsonpb.Message{Name: "gapi.testdata.pdtest.Location"}
+	m0.Fields = []jsonpb.Field{
+		{Name: "a", Kind: jsonpb.Int32Kind, Tag: 1},
+		{Name: "b", Kind: jsonpb.Int32Kind, Tag: 2},
+	}
+	m0.BakeTagIndex()
+	m0.BakeNameIndex()
+	m1.Fields = []jsonpb.Field{
+		{Name: "sum", Kind: jsonpb.Int32Kind, Tag: 1},
+	}
+	m1.BakeTagIndex()
+	m1.BakeNameIndex()
+	m2.Fields = []jsonpb.Field{
+		{Name: "what", Kind: jsonpb.StringKind, Tag: 1},
+	}
+	m2.BakeTagIndex()
+	m2.BakeNameIndex()
+	m3.Fields = []jsonpb.Field{
+		{Name: "text", Kind: jsonpb.StringKind, Tag: 1},
+		{Name: "who", Kind: jsonpb.MessageKind, Ref: m4, Tag: 2},
+		{Name: "mentions", Kind: jsonpb.MessageKind, Ref: m4, Tag: 3, Repeated: true, Omit: jsonpb.OmitEmpty},
+		{Name: "embedded", Kind: jsonpb.MessageKind, Ref: m5, Tag: 4},
+		{Name: "users", Kind: jsonpb.MapKind, Ref: m6, Tag: 5},
+		{Name: "loc", Kind: jsonpb.MessageKind, Ref: m7, Tag: 6},
+	}
+	m3.BakeTagIndex()
+	m3.BakeNameIndex()
+	m4.Fields = []jsonpb.Field{
+		{Name: "name", Kind: jsonpb.StringKind, Tag: 1},
+		{Name: "age", Kind: jsonpb.Int32Kind, Tag: 2},
+	}
+	m4.BakeTagIndex()
+	m4.BakeNameIndex()
+	m5.Fields = []jsonpb.Field{
+		{Name: "ip", Kind: jsonpb.StringKind, Tag: 1},
+	}
+	m5.BakeTagIndex()
+	m5.BakeNameIndex()
+	m6.Fields = []jsonpb.Field{
+		{Name: "key", Kind: jsonpb.StringKind, Tag: 1},
+		{Name: "value", Kind: jsonpb.MessageKind, Ref: m4, Tag: 2},
+	}
+	m6.BakeTagIndex()
+	m6.BakeNameIndex()
+	m7.Fields = []jsonpb.Field{
+		{Name: "city", Kind: jsonpb.StringKind, Tag: 1, Omit: jsonpb.OmitEmpty},
+	}
+	m7.BakeTagIndex()
+	m7.BakeNameIndex()
+
+	return []*metadata.Route{
+		{
+			Method: "POST",
+			Path:   "/path/prefix/add",
+			Use:    []string{"service-use-0", "service-use-1"},
+			Call: &metadata.Call{
+				Server:  "test-server",
+				Handler: "jsonapi",
+				Method:  "/gapi.testdata.pdtest.TestService/Add",
+				In:      m0,
+				Out:     m1,
+				Timeout: 5000 * time.Millisecond,
+			},
+		},
+		{
+			Method: "POST",
+			Path:   "/path/prefix/say",
+			Use:    []string{"service-use-0", "service-use-1", "say-use-0", "say-use-1"},
+			Call: &metadata.Call{
+				Server:  "test-server",
+				Handler: "say-handler",
+				Method:  "/gapi.testdata.pdtest.TestService/Say",
+				In:      m2,
+				Out:     m3,
+				Bindings: []metadata.FieldBinding{
+					{Name: "uid", Kind: jsonpb.StringKind, Tag: 2, Bind: metadata.BindContext},
+					{Name: "tag", Kind: jsonpb.StringKind, Tag: 3, Bind: metadata.BindQuery},
+				},
+				Timeout: 7000 * time.Millisecond,
+			},
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package pdtest
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/vizee/gapi-plus/apimeta/protodesc"
+	"github.com/vizee/gapi-plus/proto/plugintest"
+	"github.com/vizee/gapi/metadata"
+)
+
+// TestGapiRoutes 编译生成的 gapi_routes.pb.go，并与运行时解析 testdata/pdtest 的结果比较
+func TestGapiRoutes(t *testing.T) {
+	req := plugintest.Request(t, "../../../testdata/pdtest/pdtest.pd")
+	var (
+		want []*metadata.Route
+		err  error
+	)
+	p := protodesc.NewParser()
+	for _, fd := range req.ProtoFile {
+		want, err = p.AddFile(want, fd, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := GapiRoutes()
+	if !reflect.DeepEqual(got, want) {
+		gj, _ := json.MarshalIndent(got, "", "  ")
+		wj, _ := json.MarshalIndent(want, "", "  ")
+		t.Fatalf("GapiRoutes() = %s\nwant %s", gj, wj)
+	}
+}
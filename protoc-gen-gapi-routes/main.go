@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/vizee/gapi-plus/protoc-gen-gapi-routes/gen"
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	var flags flag.FlagSet
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(gen.NewGenerator(&gen.Config{
+		Out:          flags.String("out", "gapi_routes.pb.go", "output file name in the package directory"),
+		IgnoreErrors: flags.Bool("ignore_errors", false, "skip invalid services and methods instead of failing"),
+		StrictFields: flags.Bool("strict_fields", false, "reject methods referencing messages with dropped fields"),
+		Warnings:     os.Stderr,
+	}).Run)
+}
@@ -11,6 +11,13 @@ type Annotation struct {
 	lines []string
 }
 
+func (a *Annotation) Name() string {
+	if a != nil {
+		return a.name
+	}
+	return ""
+}
+
 func (a *Annotation) Line(i int) string {
 	if a != nil {
 		if i < 0 {
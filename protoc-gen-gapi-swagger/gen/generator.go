@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/go-openapi/spec"
+	"github.com/vizee/gapi-plus/httpcache"
 	"github.com/vizee/gapi-plus/protoc-gen-gapi-swagger/annotations"
 	gapiproto "github.com/vizee/gapi-proto-go/gapi"
 	"google.golang.org/protobuf/compiler/protogen"
@@ -171,6 +172,121 @@ func (g *Generator) parseMessage(msg *protogen.Message) error {
 	return nil
 }
 
+func headerType(kind protoreflect.Kind) (string, string) {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "boolean", ""
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind, protoreflect.Sfixed32Kind, protoreflect.Fixed32Kind, protoreflect.EnumKind:
+		return "integer", "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind, protoreflect.Sfixed64Kind, protoreflect.Fixed64Kind:
+		return "integer", "int64"
+	case protoreflect.FloatKind:
+		return "number", "float"
+	case protoreflect.DoubleKind:
+		return "number", "double"
+	}
+	return "string", ""
+}
+
+// parseResponseBindings 把输出消息中绑定到 header 的字段描述为成功响应的响应头，
+// 绑定到 ":status" 的字段决定响应状态码，记录在 x-gapi-status-field 扩展中。
+// 网关会把这些字段从响应体中移出，所以也从输出消息的定义中删除
+func (g *Generator) parseResponseBindings(method *protogen.Method, op *spec.Operation) {
+	def := g.doc.Definitions[string(method.Output.Desc.FullName())]
+	for _, field := range method.Output.Fields {
+		bind, _ := proto.GetExtension(field.Desc.Options(), gapiproto.E_Bind).(gapiproto.FIELD_BIND)
+		if bind != gapiproto.FIELD_BIND_FROM_HEADER {
+			continue
+		}
+		name := field.Desc.TextName()
+		alias, _ := proto.GetExtension(field.Desc.Options(), gapiproto.E_Alias).(string)
+		if alias != "" {
+			name = alias
+		}
+		delete(def.Properties, name)
+		if name == ":status" {
+			op.AddExtension("x-gapi-status-field", string(field.Desc.Name()))
+			continue
+		}
+
+		ty, format := headerType(field.Desc.Kind())
+		header := spec.Header{
+			SimpleSchema: spec.SimpleSchema{
+				Type:   ty,
+				Format: format,
+			},
+			HeaderProps: spec.HeaderProps{
+				Description: strings.TrimSpace(string(field.Comments.Leading)),
+			},
+		}
+
+		addSuccessHeader(op, name, header)
+	}
+}
+
+// addSuccessHeader 把响应头添加到所有 2xx 响应上，没有响应时添加 200 响应
+func addSuccessHeader(op *spec.Operation, name string, header spec.Header) {
+	if op.Responses == nil {
+		op.Responses = &spec.Responses{}
+	}
+	if op.Responses.StatusCodeResponses == nil {
+		op.Responses.StatusCodeResponses = make(map[int]spec.Response)
+	}
+	if len(op.Responses.StatusCodeResponses) == 0 {
+		op.Responses.StatusCodeResponses[200] = spec.Response{}
+	}
+	for code, resp := range op.Responses.StatusCodeResponses {
+		if code < 200 || code >= 300 {
+			continue
+		}
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]spec.Header)
+		}
+		resp.Headers[name] = header
+		op.Responses.StatusCodeResponses[code] = resp
+	}
+}
+
+func stringHeader(description string) spec.Header {
+	return spec.Header{
+		SimpleSchema: spec.SimpleSchema{
+			Type: "string",
+		},
+		HeaderProps: spec.HeaderProps{
+			Description: description,
+		},
+	}
+}
+
+// describeCachePolicy 根据 `@cache` 注解描述缓存相关的响应头，原始的策略记录在 x-gapi-cache 扩展中。
+// 网关只缓存 GET 和 HEAD 请求，其他方法的注解只做校验
+func describeCachePolicy(line string, method string, op *spec.Operation) error {
+	policy, err := httpcache.ParsePolicy(line)
+	if err != nil {
+		return err
+	}
+	if method != "GET" && method != "HEAD" {
+		return nil
+	}
+
+	cacheControl := policy.CacheControl()
+	addSuccessHeader(op, "Cache-Control", stringHeader(cacheControl))
+	if policy.ETag && !policy.NoStore {
+		addSuccessHeader(op, "ETag", stringHeader("响应体的版本，可以通过 If-None-Match 请求头进行条件请求"))
+	}
+	if len(policy.VaryHeaders) > 0 {
+		addSuccessHeader(op, "Vary", stringHeader(strings.Join(policy.VaryHeaders, ", ")))
+	}
+	ext := map[string]any{
+		"cache-control": cacheControl,
+	}
+	if len(policy.VaryQuery) > 0 {
+		ext["vary-query"] = policy.VaryQuery
+	}
+	op.AddExtension("x-gapi-cache", ext)
+	return nil
+}
+
 func (g *Generator) parseService(service *protogen.Service) error {
 	serviceOpts := service.Desc.Options()
 	serverName, _ := proto.GetExtension(serviceOpts, gapiproto.E_Server).(string)
@@ -213,14 +329,36 @@ func (g *Generator) parseService(service *protogen.Service) error {
 			gf(method, methodAns, op)
 		}
 
+		// @handler <consumes> <produces> <handler> 声明按媒体类型协商的 handler，方法没有声明时使用服务的声明
+		handlerBindings := methodAns.Get("handler")
+		if handlerBindings == nil {
+			handlerBindings = serviceAns.Get("handler")
+		}
+		var negotiated []string
+		for i := 0; i < handlerBindings.LineNum(); i++ {
+			fields := annotations.ParseLineFields(handlerBindings.Line(i), ' ')
+			if len(fields) != 3 {
+				return fmt.Errorf("invalid handler binding '%s' of %s", handlerBindings.Line(i), method.Desc.FullName())
+			}
+			op.Consumes = appendUnique(op.Consumes, fields[0])
+			op.Produces = appendUnique(op.Produces, fields[1])
+			negotiated = appendUnique(negotiated, fields[2])
+		}
+
 		handler := httpOpt.Handler
 		if handler == "" {
 			handler = defaultHandler
 		}
+		if handler == "" && len(negotiated) > 0 {
+			handler = negotiated[0]
+		}
 
-		hf := g.conf.Handlers[handler]
-		if hf != nil {
-			hf(method, methodAns, op)
+		// 每个 handler 的模板只应用一次
+		for _, h := range appendUnique([]string{handler}, negotiated...) {
+			hf := g.conf.Handlers[h]
+			if hf != nil {
+				hf(method, methodAns, op)
+			}
 		}
 
 		for _, use := range commonUse {
@@ -236,6 +374,33 @@ func (g *Generator) parseService(service *protogen.Service) error {
 			}
 		}
 
+		g.parseResponseBindings(method, op)
+
+		// 自定义属性原样输出到 x-gapi-attributes 扩展中，方法的属性覆盖服务的同名属性
+		attrs := make(map[string]string)
+		for _, ans := range []annotations.Annotations{serviceAns, methodAns} {
+			attr := ans.Get("attr")
+			for i := 0; i < attr.LineNum(); i++ {
+				key, value, found := strings.Cut(attr.Line(i), "=")
+				if !found {
+					value = "true"
+				}
+				attrs[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		if len(attrs) > 0 {
+			op.AddExtension("x-gapi-attributes", attrs)
+		}
+
+		// Swagger 2.0 只能描述一个 host，路由绑定的 host 模式记录在 x-gapi-hosts 扩展中
+		hosts := methodAns.Get("host")
+		if hosts == nil {
+			hosts = serviceAns.Get("host")
+		}
+		if hostList := annotations.ParseLineFields(strings.ToLower(hosts.Line(-1)), ','); len(hostList) > 0 {
+			op.AddExtension("x-gapi-hosts", hostList)
+		}
+
 		var (
 			method string
 			path   string
@@ -261,6 +426,17 @@ func (g *Generator) parseService(service *protogen.Service) error {
 			path = t.Custom.Path
 		}
 
+		cache := methodAns.Get("cache")
+		if cache == nil {
+			cache = serviceAns.Get("cache")
+		}
+		if cache.Line(-1) != "" {
+			err := describeCachePolicy(cache.Line(-1), method, op)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.ID, err)
+			}
+		}
+
 		pathItem := g.doc.Paths.Paths[pathPrefix+path]
 		switch method {
 		case "GET":
@@ -0,0 +1,101 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vizee/gapi-plus/proto/descriptor"
+	"github.com/vizee/gapi-plus/proto/plugintest"
+	"github.com/vizee/gapi-plus/protoc-gen-gapi-swagger/gapi"
+	gapiproto "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func makeRequest(t *testing.T) *pluginpb.CodeGeneratorRequest {
+	return plugintest.Request(t, "../../testdata/pdtest/pdtest.pd", "pdtest.proto")
+}
+
+// sayMethod 返回 pdtest.proto 中的 TestService.Say 和它的注释
+func sayMethod(t *testing.T, req *pluginpb.CodeGeneratorRequest) (*descriptorpb.MethodDescriptorProto, *descriptorpb.SourceCodeInfo_Location) {
+	fd := plugintest.File(t, req, "pdtest.proto")
+	return fd.Service[0].Method[1], plugintest.Location(t, fd, descriptor.MethodPath(0, 1)...)
+}
+
+func runGenerator(t *testing.T, req *pluginpb.CodeGeneratorRequest, conf *Config) (*Generator, error) {
+	out := "swagger.json"
+	conf.Out = &out
+	g := NewGenerator(conf, nil)
+	_, err := plugintest.Run(t, req, func(plugin *protogen.Plugin) error {
+		return g.Run(plugin)
+	})
+	return g, err
+}
+
+func TestResponseBindings(t *testing.T) {
+	g, err := runGenerator(t, makeRequest(t), &Config{Handlers: map[string]MethodHandler{"jsonapi": gapi.JsonAPI}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := g.Document()
+
+	props := doc.Definitions["gapi.testdata.pdtest.AddResponse"].Properties
+	if len(props) != 1 || props["sum"].Type[0] != "integer" {
+		t.Fatalf("AddResponse properties = %v", props)
+	}
+	op := doc.Paths.Paths["/path/prefix/add"].Post
+	if op == nil {
+		t.Fatal("missing POST /path/prefix/add")
+	}
+	if _, ok := op.Responses.StatusCodeResponses[200].Headers["ETag"]; !ok {
+		t.Errorf("missing ETag header: %+v", op.Responses.StatusCodeResponses[200])
+	}
+	if op.Extensions["x-gapi-status-field"] != "status" {
+		t.Errorf("extensions = %v", op.Extensions)
+	}
+}
+
+func TestCachePolicy(t *testing.T) {
+	// 网关不缓存 POST 请求，只校验注解
+	g, err := runGenerator(t, makeRequest(t), &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := g.Document().Paths.Paths["/path/prefix/say"].Post
+	if _, ok := post.Responses.StatusCodeResponses[200].Headers["Cache-Control"]; ok {
+		t.Errorf("POST should not have Cache-Control: %+v", post.Responses.StatusCodeResponses[200])
+	}
+	if _, ok := post.Extensions["x-gapi-cache"]; ok {
+		t.Errorf("POST extensions = %v", post.Extensions)
+	}
+
+	req := makeRequest(t)
+	say, _ := sayMethod(t, req)
+	rule := proto.GetExtension(say.Options, gapiproto.E_Http).(*gapiproto.Http)
+	rule.Pattern = &gapiproto.Http_Get{Get: "/say"}
+	g, err = runGenerator(t, req, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := g.Document().Paths.Paths["/path/prefix/say"].Get
+	headers := get.Responses.StatusCodeResponses[200].Headers
+	if headers["Cache-Control"].Description != "private, max-age=30" || headers["Vary"].Description != "Accept-Language" {
+		t.Errorf("GET headers = %+v", headers)
+	}
+	if _, ok := headers["ETag"]; !ok {
+		t.Errorf("GET headers = %+v", headers)
+	}
+	if _, ok := get.Extensions["x-gapi-cache"]; !ok {
+		t.Errorf("GET extensions = %v", get.Extensions)
+	}
+
+	req = makeRequest(t)
+	_, comments := sayMethod(t, req)
+	comments.LeadingComments = proto.String(" @cache immutable\n")
+	_, err = runGenerator(t, req, &Config{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cache directive 'immutable'") {
+		t.Errorf("Run() error = %v", err)
+	}
+}
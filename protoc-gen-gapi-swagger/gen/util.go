@@ -63,3 +63,19 @@ func mergeMap[K comparable, V any](dst map[K]V, src map[K]V) {
 		dst[k] = v
 	}
 }
+
+func appendUnique(s []string, vs ...string) []string {
+	for _, v := range vs {
+		found := false
+		for _, t := range s {
+			if t == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, v)
+		}
+	}
+	return s
+}
@@ -4,6 +4,8 @@ go 1.20
 
 require (
 	github.com/go-openapi/spec v0.20.9
+	github.com/vizee/gapi-plus/httpcache v0.3.0
+	github.com/vizee/gapi-plus/proto v0.3.0
 	github.com/vizee/gapi-proto-go v0.0.0-20230505112701-bca324ad1c1e
 	google.golang.org/protobuf v1.30.0
 	gopkg.in/yaml.v2 v2.4.0
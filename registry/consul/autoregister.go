@@ -0,0 +1,143 @@
+package consul
+
+import (
+	"context"
+	"time"
+
+	"github.com/vizee/gapi-plus/proto/reflection"
+	"github.com/vizee/gapi-plus/registry/consul/liteconsul"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type ShutdownPolicy int
+
+const (
+	// KeepOnShutdown 停止时保留 consul 中的文件，适合多个实例共用一个 server 名
+	KeepOnShutdown ShutdownPolicy = iota
+	// DeregisterOnShutdown 停止时删除 consul 中 server 的所有文件
+	DeregisterOnShutdown
+)
+
+const (
+	DefaultRecheckInterval = 30 * time.Second
+	DefaultMinBackoff      = time.Second
+	DefaultMaxBackoff      = 30 * time.Second
+)
+
+type AutoRegisterConfig struct {
+	Addr   string
+	Token  string
+	Prefix string
+	// RecheckInterval 是注册成功后再次检查 consul 的间隔，consul 丢失数据时会重新注册
+	RecheckInterval time.Duration
+	// MinBackoff 和 MaxBackoff 是注册失败后重试的退避时间范围
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Shutdown   ShutdownPolicy
+	// CollectOptions 传给 reflection.CollectGapiFiles
+	CollectOptions []reflection.Option
+	// OnError 不为 nil 时接收后台注册过程中的错误
+	OnError func(err error)
+}
+
+// AutoRegistrator 在后台把 grpc 服务的 gapi 描述符注册到 consul
+type AutoRegistrator struct {
+	reg    *Registrator
+	srv    reflection.ServiceInfoProvider
+	server string
+	config AutoRegisterConfig
+	// files 在第一次收集成功后缓存，之后的检查只把它重新同步到 consul，不会再收集 srv 上后来注册的服务
+	files  []*descriptorpb.FileDescriptorProto
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartAutoRegister 收集 srv 上的 gapi 描述符并在后台注册，需要在 srv 注册完所有服务后调用
+func StartAutoRegister(srv reflection.ServiceInfoProvider, server string, config *AutoRegisterConfig) *AutoRegistrator {
+	a := &AutoRegistrator{
+		reg:    NewRegistrator(liteconsul.NewClient(config.Addr, config.Token), config.Prefix),
+		srv:    srv,
+		server: server,
+		config: *config,
+		done:   make(chan struct{}),
+	}
+	if a.config.RecheckInterval <= 0 {
+		a.config.RecheckInterval = DefaultRecheckInterval
+	}
+	if a.config.MinBackoff <= 0 {
+		a.config.MinBackoff = DefaultMinBackoff
+	}
+	if a.config.MaxBackoff < a.config.MinBackoff {
+		a.config.MaxBackoff = DefaultMaxBackoff
+		if a.config.MaxBackoff < a.config.MinBackoff {
+			a.config.MaxBackoff = a.config.MinBackoff
+		}
+	}
+
+	var ctx context.Context
+	ctx, a.cancel = context.WithCancel(context.Background())
+	go a.run(ctx)
+	return a
+}
+
+func (a *AutoRegistrator) register(ctx context.Context) error {
+	if a.files == nil {
+		fds, err := reflection.CollectGapiFiles(a.srv, a.config.CollectOptions...)
+		if err != nil {
+			return err
+		}
+		a.files = fds.File
+		if a.files == nil {
+			a.files = []*descriptorpb.FileDescriptorProto{}
+		}
+	}
+	// checksum 不变的文件不会重复写入，consul 丢失数据后 checksum 也会丢失，文件会被重新写入
+	return a.reg.registerFiles(ctx, a.server, a.files, true)
+}
+
+func (a *AutoRegistrator) run(ctx context.Context) {
+	defer close(a.done)
+
+	backoff := a.config.MinBackoff
+	for {
+		wait := a.config.RecheckInterval
+		err := a.register(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if a.config.OnError != nil {
+				a.config.OnError(err)
+			}
+			wait = backoff
+			backoff *= 2
+			if backoff > a.config.MaxBackoff {
+				backoff = a.config.MaxBackoff
+			}
+		} else {
+			backoff = a.config.MinBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Stop 停止后台注册，并按照 Shutdown 策略清理 consul 中的文件
+func (a *AutoRegistrator) Stop(ctx context.Context) error {
+	a.cancel()
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if a.config.Shutdown == DeregisterOnShutdown {
+		return a.reg.DeregisterServer(ctx, a.server)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package consul
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vizee/gapi-plus/proto/reflection"
+	annotation "github.com/vizee/gapi-proto-go/gapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testDataKey = "gapi/data/test-server/registry/api.proto"
+
+func testCollectOptions(t *testing.T) []reflection.Option {
+	serviceOpts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(serviceOpts, annotation.E_Server, "test-server")
+	proto.SetExtension(serviceOpts, annotation.E_DefaultHandler, "json")
+	methodOpts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(methodOpts, annotation.E_Http, &annotation.Http{Pattern: &annotation.Http_Post{Post: "/call"}})
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(annotation.File_gapi_annotation_proto),
+			{
+				Name:        proto.String("registry/api.proto"),
+				Package:     proto.String("gapi.testdata.registry"),
+				Syntax:      proto.String("proto3"),
+				Dependency:  []string{"gapi/annotation.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Empty")}},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name:    proto.String("ApiService"),
+						Options: serviceOpts,
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       proto.String("Call"),
+								InputType:  proto.String(".gapi.testdata.registry.Empty"),
+								OutputType: proto.String(".gapi.testdata.registry.Empty"),
+								Options:    methodOpts,
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []reflection.Option{reflection.WithResolver(files)}
+}
+
+func startTestAutoRegister(t *testing.T, addr string, config *AutoRegisterConfig) *AutoRegistrator {
+	config.Addr = addr
+	config.Prefix = "gapi"
+	config.CollectOptions = testCollectOptions(t)
+	a := StartAutoRegister(reflection.ServiceNames{"gapi.testdata.registry.ApiService"}, "test-server", config)
+	t.Cleanup(func() { _ = a.Stop(context.Background()) })
+	return a
+}
+
+func TestAutoRegister(t *testing.T) {
+	f, addr := newFakeConsul(t)
+	a := startTestAutoRegister(t, addr, &AutoRegisterConfig{
+		RecheckInterval: 10 * time.Millisecond,
+		OnError:         func(err error) { t.Error(err) },
+	})
+
+	waitFor(t, "register on start", func() bool { return f.modifyIndex(testDataKey) != 0 })
+
+	// consul 丢失数据后在下一次检查时重新注册
+	f.reset()
+	waitFor(t, "re-register", func() bool {
+		return f.modifyIndex(testDataKey) != 0 && f.modifyIndex("gapi/notify") != 0
+	})
+
+	err := a.Stop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 默认的 KeepOnShutdown 保留文件
+	if f.modifyIndex(testDataKey) == 0 {
+		t.Error("KeepOnShutdown should keep files")
+	}
+}
+
+func TestAutoRegisterBackoff(t *testing.T) {
+	f, addr := newFakeConsul(t)
+	f.failures = 1 << 30
+
+	var (
+		mu     sync.Mutex
+		failed []time.Time
+	)
+	startTestAutoRegister(t, addr, &AutoRegisterConfig{
+		RecheckInterval: time.Hour,
+		MinBackoff:      10 * time.Millisecond,
+		MaxBackoff:      40 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			failed = append(failed, time.Now())
+			mu.Unlock()
+		},
+	})
+	waitFor(t, "retries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(failed) >= 5
+	})
+
+	// 计时器不会提前触发，所以只检查下限：10ms, 20ms, 40ms, 40ms
+	mu.Lock()
+	for i, want := range []time.Duration{10, 20, 40, 40} {
+		if gap := failed[i+1].Sub(failed[i]); gap < want*time.Millisecond {
+			t.Errorf("retry %d after %v, want at least %v", i+1, gap, want*time.Millisecond)
+		}
+	}
+	mu.Unlock()
+
+	// consul 恢复后完成注册
+	f.mu.Lock()
+	f.failures = 0
+	f.mu.Unlock()
+	waitFor(t, "register after recovery", func() bool { return f.modifyIndex(testDataKey) != 0 })
+}
+
+func TestAutoRegisterDeregisterOnShutdown(t *testing.T) {
+	f, addr := newFakeConsul(t)
+	a := startTestAutoRegister(t, addr, &AutoRegisterConfig{
+		Shutdown: DeregisterOnShutdown,
+		OnError:  func(err error) { t.Error(err) },
+	})
+	waitFor(t, "register on start", func() bool { return f.modifyIndex(testDataKey) != 0 })
+
+	err := a.Stop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := f.keys()
+	if len(keys) != 1 || keys[0] != "gapi/notify" {
+		t.Errorf("keys after stop = %v", keys)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,6 +17,8 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+var errChecksumConflict = errors.New("checksum has been modified concurrently")
+
 type Registrator struct {
 	client *liteconsul.Client
 	prefix string
@@ -58,7 +61,26 @@ func (r *Registrator) setFileData(chksumKey string, chksum string, dataKey strin
 	return ok, err
 }
 
-func (r *Registrator) syncFileData(ctx context.Context, server string, filename string, data []byte) (bool, error) {
+// trySyncFileData 尝试一次写入文件数据，checksum 没有变化时返回 false
+func (r *Registrator) trySyncFileData(chksumKey string, chksum string, dataKey string, data []byte) (bool, error) {
+	lastChksum, lastVer, err := r.getChecksum(chksumKey)
+	if err != nil {
+		return false, err
+	}
+	if lastChksum == chksum {
+		return false, nil
+	}
+	ok, err := r.setFileData(chksumKey, chksum, dataKey, data, lastVer)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errChecksumConflict
+	}
+	return true, nil
+}
+
+func (r *Registrator) syncFileData(ctx context.Context, server string, filename string, data []byte, once bool) (bool, error) {
 	dataKey := r.getDataKey(server, filename)
 	chksumKey := r.getChecksumKey(server, filename)
 
@@ -66,33 +88,23 @@ func (r *Registrator) syncFileData(ctx context.Context, server string, filename
 	chksum := hex.EncodeToString(sha1sum[:])
 
 	for ctx.Err() == nil {
-		lastChksum, lastVer, err := r.getChecksum(chksumKey)
-		if err != nil {
-			time.Sleep(time.Second)
-			continue
-		}
-		if lastChksum == chksum {
-			return false, nil
-		}
-
-		ok, _ := r.setFileData(chksumKey, chksum, dataKey, data, lastVer)
-		if ok {
-			break
+		ok, err := r.trySyncFileData(chksumKey, chksum, dataKey, data)
+		if err == nil || once {
+			return ok, err
 		}
 		time.Sleep(time.Second)
 	}
-	err := ctx.Err()
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+	return false, ctx.Err()
 }
 
-func (r *Registrator) notifyUpdate(ctx context.Context) error {
+func (r *Registrator) notifyUpdate(ctx context.Context, once bool) error {
 	key := fmt.Sprintf("%s/notify", r.prefix)
 	for ctx.Err() == nil {
 		_, err := r.client.KV().Put(key, []byte(strconv.Itoa(int(time.Now().UnixNano()))))
 		if err != nil {
+			if once {
+				return err
+			}
 			time.Sleep(time.Second)
 			continue
 		}
@@ -102,7 +114,12 @@ func (r *Registrator) notifyUpdate(ctx context.Context) error {
 	return ctx.Err()
 }
 
+// RegisterFiles 把 files 写入 consul，出错时会一直重试直到 ctx 结束
 func (r *Registrator) RegisterFiles(ctx context.Context, server string, files []*descriptorpb.FileDescriptorProto) error {
+	return r.registerFiles(ctx, server, files, false)
+}
+
+func (r *Registrator) registerFiles(ctx context.Context, server string, files []*descriptorpb.FileDescriptorProto, once bool) error {
 	if len(files) == 0 {
 		return nil
 	}
@@ -123,7 +140,7 @@ func (r *Registrator) RegisterFiles(ctx context.Context, server string, files []
 		if err != nil {
 			return err
 		}
-		ok, err := r.syncFileData(ctx, server, f.GetName(), compressbuf.Bytes())
+		ok, err := r.syncFileData(ctx, server, f.GetName(), compressbuf.Bytes(), once)
 		if err != nil {
 			return err
 		}
@@ -132,11 +149,26 @@ func (r *Registrator) RegisterFiles(ctx context.Context, server string, files []
 		}
 	}
 	if r.update {
-		return r.notifyUpdate(ctx)
+		return r.notifyUpdate(ctx, once)
 	}
 	return nil
 }
 
+// DeregisterServer 删除 server 的所有文件并通知网关
+func (r *Registrator) DeregisterServer(ctx context.Context, server string) error {
+	for _, key := range []string{r.getDataKey(server, ""), r.getChecksumKey(server, "")} {
+		_, err := r.client.KV().Delete(key, "recurse", "true")
+		if err != nil {
+			return err
+		}
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+	}
+	return r.notifyUpdate(ctx, true)
+}
+
 func NewRegistrator(client *liteconsul.Client, prefix string) *Registrator {
 	return &Registrator{
 		client: client,
@@ -0,0 +1,229 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vizee/gapi-plus/registry/consul/liteconsul"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// fakeConsul 模拟 consul 的 KV 和 Txn 接口
+type fakeConsul struct {
+	mu    sync.Mutex
+	index uint64
+	kv    map[string]*liteconsul.KVEntry
+	// failures 是接下来需要返回 500 的请求数
+	failures int
+	deletes  []string
+}
+
+func newFakeConsul(t *testing.T) (*fakeConsul, string) {
+	f := &fakeConsul{kv: make(map[string]*liteconsul.KVEntry)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func (f *fakeConsul) set(key string, value []byte) {
+	f.index++
+	e := f.kv[key]
+	if e == nil {
+		e = &liteconsul.KVEntry{Key: key, CreateIndex: f.index}
+		f.kv[key] = e
+	}
+	e.Value = value
+	e.ModifyIndex = f.index
+}
+
+// casOK 与 consul 一致，index 为 0 时要求 key 不存在
+func (f *fakeConsul) casOK(key string, index uint64) bool {
+	e := f.kv[key]
+	if e == nil {
+		return index == 0
+	}
+	return e.ModifyIndex == index
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+
+	if r.URL.Path == "/v1/txn" {
+		var ops []struct{ KV liteconsul.TxnOpKV }
+		if err := json.Unmarshal(body, &ops); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var resp liteconsul.TxnResponse
+		for i, op := range ops {
+			if op.KV.Verb == liteconsul.VerbCAS && !f.casOK(op.KV.Key, op.KV.Index) {
+				resp.Errors = append(resp.Errors, liteconsul.TxnError{OpIndex: i, What: "index mismatch"})
+			}
+		}
+		if len(resp.Errors) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(&resp)
+			return
+		}
+		for _, op := range ops {
+			f.set(op.KV.Key, op.KV.Value)
+		}
+		_ = json.NewEncoder(w).Encode(&resp)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	recurse := r.URL.Query().Get("recurse") == "true"
+	switch r.Method {
+	case http.MethodGet:
+		var ents []liteconsul.KVEntry
+		for k, e := range f.kv {
+			if k == key || recurse && strings.HasPrefix(k, key) {
+				ents = append(ents, *e)
+			}
+		}
+		if len(ents) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		sort.Slice(ents, func(i, j int) bool { return ents[i].Key < ents[j].Key })
+		_ = json.NewEncoder(w).Encode(ents)
+	case http.MethodPut:
+		if cas := r.URL.Query().Get("cas"); cas != "" {
+			index, _ := strconv.ParseUint(cas, 10, 64)
+			if !f.casOK(key, index) {
+				_, _ = w.Write([]byte("false"))
+				return
+			}
+		}
+		f.set(key, body)
+		_, _ = w.Write([]byte("true"))
+	case http.MethodDelete:
+		f.deletes = append(f.deletes, r.URL.RawQuery)
+		for k := range f.kv {
+			if k == key || recurse && strings.HasPrefix(k, key) {
+				delete(f.kv, k)
+			}
+		}
+		_, _ = w.Write([]byte("true"))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeConsul) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.kv))
+	for k := range f.kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeConsul) modifyIndex(key string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e := f.kv[key]; e != nil {
+		return e.ModifyIndex
+	}
+	return 0
+}
+
+func (f *fakeConsul) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kv = make(map[string]*liteconsul.KVEntry)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegistrator(t *testing.T) {
+	f, addr := newFakeConsul(t)
+	r := NewRegistrator(liteconsul.NewClient(addr, ""), "/gapi")
+	files := []*descriptorpb.FileDescriptorProto{
+		{Name: proto.String("a.proto"), Package: proto.String("a")},
+		{Name: proto.String("b.proto"), Package: proto.String("b")},
+	}
+	ctx := context.Background()
+
+	err := r.RegisterFiles(ctx, "api", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.RegisterFiles(ctx, "api-admin", files[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gapi/checksum/api-admin/a.proto",
+		"gapi/checksum/api/a.proto",
+		"gapi/checksum/api/b.proto",
+		"gapi/data/api-admin/a.proto",
+		"gapi/data/api/a.proto",
+		"gapi/data/api/b.proto",
+		"gapi/notify",
+	}
+	if got := f.keys(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("keys = %v, want %v", got, want)
+	}
+
+	// checksum 没有变化时不重复写入，也不通知网关
+	dataIndex, notifyIndex := f.modifyIndex("gapi/data/api/a.proto"), f.modifyIndex("gapi/notify")
+	err = r.RegisterFiles(ctx, "api", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.modifyIndex("gapi/data/api/a.proto") != dataIndex || f.modifyIndex("gapi/notify") != notifyIndex {
+		t.Error("unchanged files should not be written again")
+	}
+
+	// 递归删除 api 的文件，不能影响前缀相同的 api-admin
+	err = r.DeregisterServer(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"gapi/checksum/api-admin/a.proto",
+		"gapi/data/api-admin/a.proto",
+		"gapi/notify",
+	}
+	if got := f.keys(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("keys after deregister = %v, want %v", got, want)
+	}
+	if len(f.deletes) != 2 || f.deletes[0] != "recurse=true" || f.deletes[1] != "recurse=true" {
+		t.Errorf("deletes = %v", f.deletes)
+	}
+	if f.modifyIndex("gapi/notify") == notifyIndex {
+		t.Error("deregister should notify gateway")
+	}
+}